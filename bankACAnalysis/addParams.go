@@ -1,11 +1,41 @@
 package main
 
 import (
+	"errors"
+	"strconv"
+	"strings"
+
 	"github.com/nickwells/check.mod/v2/check"
 	"github.com/nickwells/filecheck.mod/filecheck"
 	"github.com/nickwells/location.mod/location"
 	"github.com/nickwells/param.mod/v7/param"
 	"github.com/nickwells/param.mod/v7/psetter"
+	"github.com/nickwells/personal-utils/bankACAnalysis/bankac"
+)
+
+const (
+	paramNameMapFormat    = "map-format"
+	paramNameCSVLayout    = "csv-layout"
+	paramNameCSVLayoutDef = "csv-layout-def"
+	paramNameFrom         = "from"
+	paramNameTo           = "to"
+	paramNameNamedPeriod  = "named-period"
+
+	paramNameCmpFrom        = "compare-from"
+	paramNameCmpTo          = "compare-to"
+	paramNameCmpNamedPeriod = "compare-named-period"
+	paramNameCmpFiles       = "compare-files"
+
+	paramNameStore  = "store"
+	paramNameImport = "import"
+
+	paramNameCurrency     = "currency"
+	paramNameFileCurrency = "file-currency"
+	paramNameRptCurrency  = "report-currency"
+	paramNameRatesFile    = "rates-file"
+	paramNameOwnAccount   = "own-account"
+	paramNameTransfers    = "transfers"
+	paramNameTransferDays = "transfer-days"
 )
 
 // addParams will add parameters to the passed ParamSet
@@ -21,7 +51,7 @@ func addParams(prog *prog) func(ps *param.PSet) error {
 				ps.TerminalParam()+" parameter."+
 				"\n"+
 				" The file is expected to contain lines of comma-separated"+
-				" values with the values as follows:\n\n"+
+				" values. By default the values are as follows:\n\n"+
 				"transaction date in the form DD/MM/YYYY\n"+
 				"transaction type\n"+
 				"sort-code\n"+
@@ -29,7 +59,92 @@ func addParams(prog *prog) func(ps *param.PSet) error {
 				"transaction description\n"+
 				"debit amount\n"+
 				"credit amount\n"+
-				"balance",
+				"balance\n\n"+
+				"but a different layout can be chosen",
+			param.SeeAlso(paramNameCSVLayout),
+		)
+
+		ps.Add(paramNameCSVLayoutDef,
+			psetter.StrListAppender[string]{
+				Value: &prog.layoutDefs,
+			},
+			"define a named layout for the comma-separated values in"+
+				" the bank account files. This is intended to be given"+
+				" in the configuration file so that the layouts for"+
+				" each of your banks are always available.\n\n"+
+				"The value should be the name of the layout followed by"+
+				" a colon (':') and then a comma-separated list of"+
+				" field=column entries. The column can be given either"+
+				" as a column number (starting from 1) or as the name of"+
+				" the column in the header line. The fields are:\n\n"+
+				bankac.FieldDate+" (required)\n"+
+				bankac.FieldType+"\n"+
+				bankac.FieldSortCode+"\n"+
+				bankac.FieldAccount+"\n"+
+				bankac.FieldDesc+" (required)\n"+
+				bankac.FieldDebit+"\n"+
+				bankac.FieldCredit+"\n"+
+				bankac.FieldBalance+"\n"+
+				bankac.FieldAmount+"\n"+
+				bankac.FieldCurrency+"\n\n"+
+				"Either both the "+bankac.FieldDebit+" and "+bankac.FieldCredit+
+				" columns or a single, signed, "+bankac.FieldAmount+" column"+
+				" must be given. A negative amount is a debit.\n\n"+
+				"You can also give a '"+bankac.LayoutDateFormat+"'"+
+				" entry to set the layout of the dates, using the Go"+
+				" time package format (the default is "+
+				bankac.DfltDateFormat+"). A part with no '=' is taken"+
+				" as part of the value before it so the date format,"+
+				" or a column name, can include a comma."+
+				"\n\n"+
+				"For instance:\n\n"+
+				"mybank:date=Date,desc=Description,amount=Amount,"+
+				bankac.LayoutDateFormat+"=2006-01-02",
+			param.AltNames("csv-layout-definition"),
+			param.SeeAlso(paramNameCSVLayout),
+		)
+
+		ps.Add(paramNameCSVLayout,
+			psetter.String[string]{
+				Value: &prog.layoutName,
+			},
+			"the name of the layout of the comma-separated values in"+
+				" the bank account files. The layout named '"+
+				bankac.DfltLayoutName+"' is always available, others can be"+
+				" defined with the "+paramNameCSVLayoutDef+" parameter",
+			param.SeeAlso(paramNameCSVLayoutDef),
+		)
+
+		ps.AddFinalCheck(prog.setLayouts)
+
+		ps.Add("format",
+			psetter.Enum[string]{
+				Value: &prog.fileFormat,
+				AllowedVals: psetter.AllowedVals[string]{
+					fmtAuto: "choose the format from the file extension." +
+						" Files ending in '.ofx' or '.qfx' are read as" +
+						" OFX, files ending in '.qif' are read as QIF" +
+						" and all other files are read as CSV",
+					bankac.FmtCSV: "comma-separated values, see the " +
+						paramNameCSVLayout + " parameter",
+					bankac.FmtOFX: "Open Financial Exchange (OFX or QFX) files",
+					bankac.FmtQIF: "Quicken Interchange Format (QIF) files",
+				},
+			},
+			"the format of the bank account files",
+			param.AltNames("file-format"),
+		)
+
+		ps.Add("qif-date-format",
+			psetter.String[string]{
+				Value: &prog.reader.QIFDateFormat,
+				Checks: []check.String{
+					check.StringLength[string](check.ValGT(0)),
+				},
+			},
+			"the layout of the dates in QIF files, using the Go time"+
+				" package format. Any apostrophe in the date is"+
+				" replaced with a '/' before the date is parsed",
 		)
 
 		ps.Add("map-file",
@@ -42,9 +157,9 @@ func addParams(prog *prog) func(ps *param.PSet) error {
 				" representing the 'parent' group of transactions"+
 				" followed by a space and the rest of the line which"+
 				" represents the 'child' group of transactions.\n\n"+
-				"There is an initial group called '"+catAll+"' with a child,"+
-				" called '"+catUnknown+"' and the entries in this file are"+
-				" intended"+
+				"There is an initial group called '"+bankac.CatAll+"'"+
+				" with a child, called '"+bankac.CatUnknown+"' and the"+
+				" entries in this file are intended"+
 				" to construct the tree of transaction groups. Any"+
 				" transaction description which is not found in this map"+
 				" will automatically be placed in the 'unknown' group so you"+
@@ -55,8 +170,46 @@ func addParams(prog *prog) func(ps *param.PSet) error {
 				" group name. Then each transaction that you want to put in"+
 				" that group should have an entry with parent set to the"+
 				" group name and the child set to the transaction"+
-				" description. Groups can be nested to an arbitrary depth.",
-			param.Attrs(param.MustBeSet))
+				" description. Groups can be nested to an arbitrary depth."+
+				"\n\n"+
+				"The map can also be given in a nested form, see the"+
+				" "+paramNameMapFormat+" parameter",
+			param.Attrs(param.MustBeSet),
+			param.SeeAlso(paramNameMapFormat))
+
+		ps.Add(paramNameMapFormat,
+			psetter.Enum[string]{
+				Value: &prog.mapFormat,
+				AllowedVals: psetter.AllowedVals[string]{
+					mapFmtFlat: "each line gives the parent followed by" +
+						" a space and then the child",
+					mapFmtNested: "the entries are nested by their" +
+						" indentation. A line ending with a ':' gives" +
+						" a category and a line starting with '" +
+						bankac.NestedDescPrefix + "' gives a transaction" +
+						" description. A line starting with '" +
+						bankac.NestedPatternPrefix + "' gives a regular" +
+						" expression; a description which is not in the" +
+						" map is put in the category of the first" +
+						" pattern that matches it. Each entry belongs to" +
+						" the nearest preceding category which is" +
+						" indented less than it; entries which are not" +
+						" indented belong to the '" + bankac.CatAll +
+						"' category. Blank lines and lines starting with" +
+						" '#' are ignored",
+				},
+			},
+			"the format of the map file",
+			param.SeeAlso("map-file", "convert-map"),
+		)
+
+		ps.Add("convert-map",
+			psetter.Bool{Value: &prog.convertMapFlag},
+			"write the map in the nested form rather than report the"+
+				" transactions. The map is written to the output file,"+
+				" if given, or else to the standard output",
+			param.SeeAlso(paramNameMapFormat, "output-file"),
+		)
 
 		ps.Add("edit-file",
 			psetter.Pathname{
@@ -67,22 +220,214 @@ func addParams(prog *prog) func(ps *param.PSet) error {
 				" replacements. Transaction descriptions that are not mapped"+
 				" will be edited according to the rules in this file.\n\n"+
 				"Each editing rule is given by a pair of lines,"+
-				" the first must start with '"+editTypeSearch+"='"+
-				" and the second must start with '"+editTypeReplace+"='."+
+				" the first must start with '"+bankac.EditTypeSearch+"='"+
+				" and the second must start with"+
+				" '"+bankac.EditTypeReplace+"='."+
 				" The first line value should be a valid regular expression",
 			param.Attrs(param.MustBeSet))
 
+		ps.Add("rules-file",
+			psetter.Pathname{
+				Value:       &prog.rulesFileName,
+				Expectation: filecheck.FileExists(),
+			},
+			"the name of the file containing the rules used to"+
+				" categorise transactions. The rules are tried in order"+
+				" and the first rule that matches a transaction gives"+
+				" its category. The rules are tried before the"+
+				" transaction map and the transaction description"+
+				" is matched after it has been edited.\n\n"+
+				"Each rule is given by one or more lines giving the"+
+				" tests that the transaction must pass followed by a"+
+				" line giving the category. Each line is of the form"+
+				" key=value and the keys are as follows:\n\n"+
+				bankac.RuleKeyDesc+": a regular expression which must match"+
+				" the description\n"+
+				bankac.RuleKeyType+": a comma-separated list of transaction"+
+				" types (such as DD, SO or CPT), one of which must match\n"+
+				bankac.RuleKeyAmount+": a range of amounts, min:max, either of"+
+				" which may be missing. A single value must be matched"+
+				" exactly\n"+
+				bankac.RuleKeyDay+": a range of days of the month, first:last,"+
+				" in the same form as the amount\n"+
+				bankac.RuleKeyCategory+": the category, this must appear in"+
+				" the transaction map. This ends the rule.\n"+
+				bankac.RuleKeyTag+": a comma-separated list of tags. This ends"+
+				" the rule which, rather than giving a category, gives"+
+				" the tags to every transaction which matches it. All"+
+				" the tag rules are tried, not just the first to"+
+				" match.\n\n"+
+				"Blank lines and lines starting with '#' are ignored."+
+				" Use the verbose parameter to see which rule matched"+
+				" each transaction.",
+			param.AltNames("rules"),
+		)
+
+		ps.Add("split-file",
+			psetter.Pathname{
+				Value:       &prog.splitFileName,
+				Expectation: filecheck.FileExists(),
+			},
+			"the name of the file giving the transactions to be spread"+
+				" over several categories. Each line gives the"+
+				" description, after it has been edited, then an '='"+
+				" and then a comma-separated list of category:percentage"+
+				" pairs, for instance:\n\n"+
+				"TESCO=food:70,household:30\n\n"+
+				"The categories must appear in the transaction map and"+
+				" the percentages must add up to 100. Each part of a"+
+				" split transaction is added to its category but the"+
+				" transaction is only counted once in any category"+
+				" holding more than one part. Blank lines and lines"+
+				" starting with '#' are ignored",
+			param.AltNames("splits"),
+			param.SeeAlso("split-override-file"),
+		)
+
+		ps.Add("split-override-file",
+			psetter.Pathname{
+				Value:       &prog.splitOverrideFileName,
+				Expectation: filecheck.FileExists(),
+			},
+			"the name of the file giving the splits of individual"+
+				" transactions. Each line gives the date and the amount"+
+				" of the transaction, separated by a space, then an '='"+
+				" and then a comma-separated list of category:amount"+
+				" pairs, for instance:\n\n"+
+				"2024-02-01 85.20=food:60,household:25.20\n\n"+
+				"The amounts must add up to the amount of the"+
+				" transaction. These splits take precedence over those"+
+				" in the split file",
+			param.AltNames("split-overrides"),
+			param.SeeAlso("split-file"),
+		)
+
+		ps.Add("tag-file",
+			psetter.Pathname{
+				Value:       &prog.tagFileName,
+				Expectation: filecheck.FileExists(),
+			},
+			"the name of the file giving the tags of transactions."+
+				" Tags are independent of the categories and a"+
+				" transaction may have any number of them. Each line"+
+				" gives either a description, after it has been edited,"+
+				" or the date and the amount of a single transaction,"+
+				" separated by a space, then an '=' and then a"+
+				" comma-separated list of tags, for instance:\n\n"+
+				"EASYJET=holiday 2026,travel\n"+
+				"2024-02-01 85.20=tax-deductible\n\n"+
+				"Tags can also be given by rules in the rules file."+
+				" Blank lines and lines starting with '#' are ignored",
+			param.AltNames("tags"),
+			param.SeeAlso("tag-report", "rules-file"),
+		)
+
+		ps.Add("tag-report",
+			psetter.Bool{Value: &prog.tagReport},
+			"report the totals of the transactions with each tag"+
+				" rather than summarise them by category. Beneath each"+
+				" tag the totals are shown for each category in which"+
+				" the tagged transactions are found",
+			param.AltNames("tags-report"),
+			param.SeeAlso("tag-file", "rules-file"),
+		)
+
+		ps.Add("classify",
+			psetter.Bool{Value: &prog.classify},
+			"rather than reporting, walk through the distinct"+
+				" descriptions of the transactions in the '"+bankac.CatUnknown+
+				"' group, largest first, asking for the category"+
+				" of each. You can choose an existing category by number"+
+				" or name or give the name of a new category in which case"+
+				" you will be asked for its parent. The answers are"+
+				" appended to the transaction map file so that they"+
+				" will be used the next time the program runs",
+			param.AltNames("interactive"),
+			param.SeeAlso("map-file"),
+		)
+
+		ps.Add("budget-file",
+			psetter.Pathname{
+				Value:       &prog.budgetFileName,
+				Expectation: filecheck.FileExists(),
+			},
+			"the name of the file containing the budget for each"+
+				" category. If this is given a budget report is shown"+
+				" giving, for each category, the budget, the actual"+
+				" spending (debits less credits), the variance and the"+
+				" percentage of the budget used. Categories which are"+
+				" over budget are flagged.\n\n"+
+				"Each non-blank line in the file (other than those"+
+				" starting with '#') should have the category, the"+
+				" period and the amount separated by spaces. The"+
+				" period can be '"+budgetMonthly+"', '"+budgetQuarterly+
+				"' or '"+budgetYearly+"' for a recurring budget or"+
+				" a named period (see the "+paramNameNamedPeriod+
+				" parameter) for a one-off budget. The budget is"+
+				" calculated over the dates being reported; a recurring"+
+				" budget is multiplied by the number of periods and a"+
+				" one-off budget is reduced in proportion to the days"+
+				" reported. A category without a budget of its own"+
+				" has the total of the budgets of its sub-categories."+
+				"\n\n"+
+				"The budgets are for spending so income should be"+
+				" given as a negative amount; a category is over budget"+
+				" if the amount spent is greater than the budget or,"+
+				" for income, if less is received than expected.",
+			param.AltNames("budget"),
+		)
+
 		ps.Add("show-zeroes",
 			psetter.Bool{Value: &prog.showZeros},
 			"don't suppress entries which have no transactions")
 
 		ps.Add("dont-skip-line1",
 			psetter.Bool{
-				Value:  &prog.skipFirstLine,
+				Value:  &prog.reader.SkipFirstLine,
 				Invert: true,
 			},
 			"don't ignore the first line of the transactions file")
 
+		ps.Add("overlap-is-error",
+			psetter.Bool{Value: &prog.overlapIsError},
+			"treat transactions which appear in more than one of the"+
+				" bank account files as an error. Otherwise each such"+
+				" transaction is only counted once and the overlap"+
+				" between the files is reported. Transactions are the"+
+				" same if they have the same date, type, description,"+
+				" amounts and balance",
+			param.AltNames("no-overlap"),
+		)
+
+		ps.Add("reconcile",
+			psetter.Bool{Value: &prog.checkBalances},
+			"check the balances in the bank account files. In each"+
+				" file, taken in date order, the balance on each line"+
+				" should be the previous balance plus the credit less"+
+				" the debit. Between consecutive files for the same"+
+				" account the closing balance of one should be the"+
+				" opening balance of the next and there should not be"+
+				" too long a gap between the dates. Any problems are"+
+				" reported with the file name and line number. Files"+
+				" without balances are not checked.",
+			param.AltNames("check-balances"),
+			param.SeeAlso("max-gap-days"),
+		)
+
+		ps.Add("max-gap-days",
+			psetter.Int[int]{
+				Value: &prog.maxGapDays,
+				Checks: []check.ValCk[int]{
+					check.ValGE(0),
+				},
+			},
+			"the largest number of days between the last transaction"+
+				" in one file and the first transaction in the next"+
+				" file for the same account that will not be"+
+				" reported as a gap when checking the balances",
+			param.SeeAlso("reconcile"),
+		)
+
 		ps.Add("summary", psetter.Nil{},
 			"show a summary report with no leaf transactions",
 			param.PostAction(
@@ -103,6 +448,605 @@ func addParams(prog *prog) func(ps *param.PSet) error {
 			param.AltNames("show-cats", "cats"),
 		)
 
+		ps.Add("period-report",
+			psetter.Enum[string]{
+				Value: &prog.periodBy,
+				AllowedVals: psetter.AllowedVals[string]{
+					periodNone:    "don't break the report down by period",
+					periodMonth:   "show a column for each calendar month",
+					periodQuarter: "show a column for each calendar quarter",
+					periodYear:    "show a column for each calendar year",
+				},
+			},
+			"show a report with the transactions broken down by period."+
+				" There will be a column for each period from the"+
+				" first transaction to the last with a final column"+
+				" for the total",
+			param.AltNames("by-period", "period"),
+			param.SeeAlso("period-value"),
+		)
+
+		ps.Add("period-value",
+			psetter.Enum[string]{
+				Value: &prog.periodValue,
+				AllowedVals: psetter.AllowedVals[string]{
+					valDebit:  "show the debit amounts",
+					valCredit: "show the credit amounts",
+					valNet: "show the nett amount" +
+						" (credit less debit)",
+				},
+			},
+			"the value to show for each period in the period report,"+
+				" in the comparison report and in the charts",
+			param.SeeAlso("period-report", paramNameCmpFrom, "chart"),
+		)
+
+		ps.Add(paramNameFrom,
+			psetter.Time{
+				Value:  &prog.dates.from,
+				Format: paramDateFormat,
+			},
+			"ignore any transactions before this date",
+			param.SeeAlso(paramNameTo, paramNameNamedPeriod),
+		)
+
+		ps.Add(paramNameTo,
+			psetter.Time{
+				Value:  &prog.dates.to,
+				Format: paramDateFormat,
+			},
+			"ignore any transactions after this date",
+			param.SeeAlso(paramNameFrom, paramNameNamedPeriod),
+		)
+
+		ps.Add(paramNameNamedPeriod,
+			psetter.String[string]{
+				Value: &prog.namedPeriod,
+			},
+			"only report the transactions in the named period."+
+				" The period can be given as:\n\n"+
+				"a calendar year (YYYY)\n"+
+				"a calendar quarter (YYYY-Qn)\n"+
+				"a calendar month (YYYY-MM)\n"+
+				"a UK tax year ("+taxYearPrefix+"YYYY)"+
+				" which starts on the 6th of April in the given year",
+			param.AltNames("for-period", "for"),
+			param.SeeAlso(paramNameFrom, paramNameTo),
+		)
+
+		ps.AddFinalCheck(func() error {
+			if prog.namedPeriod == "" {
+				return prog.dates.check()
+			}
+
+			if prog.dates.isSet() {
+				return errors.New("the " + paramNameNamedPeriod +
+					" parameter cannot be given with either the " +
+					paramNameFrom + " or the " + paramNameTo + " parameter")
+			}
+
+			var err error
+
+			prog.dates, err = parseNamedPeriod(prog.namedPeriod)
+
+			return err
+		})
+
+		ps.Add("list-transactions",
+			psetter.Bool{Value: &prog.listXactns},
+			"list the transactions which make up each of the"+
+				" categories being shown, including those in any"+
+				" of their sub-categories, rather than summarise them."+
+				" For each transaction the date, the file and line"+
+				" where it was found, the original and the normalised"+
+				" descriptions, the lines of the edits which changed the"+
+				" description, the category and the amounts are shown",
+			param.AltNames("list-xactns", "list", "drill-down"),
+			param.SeeAlso("show-categories", "list-order"),
+		)
+
+		ps.Add("list-order",
+			psetter.Enum[string]{
+				Value: &prog.listOrder,
+				AllowedVals: psetter.AllowedVals[string]{
+					listByDate:   "list the earliest transaction first",
+					listByAmount: "list the largest transaction first",
+				},
+			},
+			"the order in which the transactions are listed",
+			param.SeeAlso("list-transactions"),
+		)
+
+		ps.Add("edit-report",
+			psetter.Bool{Value: &prog.editReport},
+			"report the use made of each of the edits rather than"+
+				" summarise the transactions. For each edit the number"+
+				" of transactions whose description it changed, the"+
+				" number of distinct original descriptions and the total"+
+				" amounts are shown. This can help to find edits which"+
+				" change more descriptions than intended",
+			param.AltNames("edits-report"),
+			param.SeeAlso("edit-file", "list-transactions"),
+		)
+
+		ps.Add("recurring-report",
+			psetter.Bool{Value: &prog.recurring},
+			"report the payments which recur at regular intervals"+
+				" (weekly, fortnightly, monthly, quarterly or yearly)"+
+				" for similar amounts; a single change in the amount,"+
+				" such as a price rise, is allowed. For each one the"+
+				" typical amount, how often it is paid, the date of the"+
+				" last payment and the date the next is expected are"+
+				" shown. The typical amount is that paid before any"+
+				" change and any payment whose latest amount differs"+
+				" from it is flagged as "+amountChangedFlag,
+			param.AltNames("recurring", "subscriptions"),
+			param.SeeAlso("recurring-min-count"),
+		)
+
+		ps.Add("recurring-min-count",
+			psetter.Int[int]{
+				Value: &prog.recurringMinCount,
+				Checks: []check.ValCk[int]{
+					check.ValGE(2),
+				},
+			},
+			"the smallest number of payments which will be taken as"+
+				" recurring",
+			param.SeeAlso("recurring-report"),
+		)
+
+		ps.Add("anomaly-report",
+			psetter.Bool{Value: &prog.anomalyReport},
+			"list the transactions which look out of character rather"+
+				" than summarise them. Each is shown with its location"+
+				" and the reasons it was flagged. A transaction is"+
+				" flagged if its amount is far above the usual range"+
+				" for its category, if it is the first payment to a"+
+				" payee and is above the new payee minimum, if it"+
+				" repeats a payment to the same payee on the same day"+
+				" for the same amount or if it is paid on an unusual"+
+				" day of the month for the payee. Payees are not taken"+
+				" as new in the first "+
+				strconv.Itoa(newPayeeGraceDays)+" days of the"+
+				" transactions",
+			param.AltNames("anomalies", "review"),
+			param.SeeAlso("anomaly-factor", "new-payee-min"),
+		)
+
+		ps.Add("anomaly-factor",
+			psetter.Float[float64]{
+				Value: &prog.anomalyFactor,
+				Checks: []check.ValCk[float64]{
+					check.ValGT(0.0),
+				},
+			},
+			"the number of times the spread of the amounts in a"+
+				" category that an amount must be above the median"+
+				" to be flagged as out of character. The spread is"+
+				" the median absolute deviation, scaled to match the"+
+				" standard deviation",
+			param.SeeAlso("anomaly-report"),
+		)
+
+		ps.Add("new-payee-min",
+			psetter.Float[float64]{
+				Value: &prog.newPayeeMin,
+				Checks: []check.ValCk[float64]{
+					check.ValGE(0.0),
+				},
+			},
+			"the smallest first payment to a payee which is flagged"+
+				" as out of character",
+			param.SeeAlso("anomaly-report"),
+		)
+
+		ps.Add("forecast",
+			psetter.Bool{Value: &prog.forecast},
+			"forecast the balance of the accounts rather than"+
+				" summarise the transactions. The forecast starts from"+
+				" the latest balance of each account and adds the"+
+				" expected recurring payments and receipts, as found"+
+				" for the recurring report, together with any planned"+
+				" items. A recurring payment which is more than one"+
+				" period overdue is taken to have stopped. Where an"+
+				" account file does not give the account, files with"+
+				" the same currency are taken to be of the same"+
+				" account. Each account is projected from the date of"+
+				" its own latest balance and the items expected since"+
+				" then are added to the opening balance. The balance is"+
+				" shown day by day with the items expected on each day"+
+				" and any balance, including the opening balance, below"+
+				" the threshold is flagged as "+lowBalanceFlag,
+			param.AltNames("cash-flow"),
+			param.SeeAlso("forecast-months", "forecast-threshold",
+				"planned-items-file", "recurring-min-count"),
+		)
+
+		ps.Add("forecast-months",
+			psetter.Int[int]{
+				Value: &prog.forecastMonths,
+				Checks: []check.ValCk[int]{
+					check.ValGE(1),
+				},
+			},
+			"the number of months after the latest transaction for"+
+				" which the balance is forecast",
+			param.SeeAlso("forecast"),
+		)
+
+		ps.Add("forecast-threshold",
+			psetter.Float[float64]{Value: &prog.forecastThreshold},
+			"the balance below which the forecast balance is flagged",
+			param.AltNames("low-balance"),
+			param.SeeAlso("forecast"),
+		)
+
+		ps.Add("planned-items-file",
+			psetter.Pathname{
+				Value:       &prog.plannedFileName,
+				Expectation: filecheck.FileExists(),
+			},
+			"the name of the file giving one-off items to be included"+
+				" in the forecast. Each line gives the date, the amount"+
+				" and a description separated by spaces. Payments should"+
+				" be given as negative amounts, for instance:\n\n"+
+				"2026-11-20 -450.00 car service\n\n"+
+				"Blank lines and lines starting with '#' are ignored",
+			param.AltNames("planned-items", "planned-file"),
+			param.SeeAlso("forecast"),
+		)
+
+		ps.Add("chart",
+			psetter.Bool{Value: &prog.chart},
+			"draw charts of the categories rather than summarise"+
+				" them. For each category a bar chart of the largest"+
+				" entries at the chart depth below it is drawn,"+
+				" followed by a bar for each month and a sparkline"+
+				" of the monthly values. The period value gives the"+
+				" amounts to chart; the length of each bar shows the"+
+				" size of the amount",
+			param.AltNames("charts"),
+			param.SeeAlso("chart-depth", "chart-top", "chart-width",
+				"period-value"),
+		)
+
+		ps.Add("chart-depth",
+			psetter.Int[int]{
+				Value: &prog.chartDepth,
+				Checks: []check.ValCk[int]{
+					check.ValGE(1),
+				},
+			},
+			"the depth in the category tree, below the category"+
+				" being charted, of the entries shown in the bar chart."+
+				" Entries with no sub-categories above this depth are"+
+				" also shown",
+			param.SeeAlso("chart"),
+		)
+
+		ps.Add("chart-top",
+			psetter.Int[int]{
+				Value: &prog.chartTop,
+				Checks: []check.ValCk[int]{
+					check.ValGE(1),
+				},
+			},
+			"the number of entries shown in the bar chart. Any"+
+				" remaining entries are shown together in a final bar",
+			param.SeeAlso("chart"),
+		)
+
+		ps.Add("chart-width",
+			psetter.Int[int]{
+				Value: &prog.chartCols,
+				Checks: []check.ValCk[int]{
+					check.ValGE(0),
+				},
+			},
+			"the width of the charts. If this is not given the"+
+				" width of the terminal is used",
+			param.SeeAlso("chart"),
+		)
+
+		ps.Add("check-config",
+			psetter.Bool{Value: &prog.checkCfg},
+			"check the transaction map and edit files and report any"+
+				" problems rather than report the transactions. If"+
+				" any account files are given the transactions are"+
+				" used to find edits which never match or are shadowed"+
+				" by earlier edits, edits giving descriptions which are"+
+				" not in the map and map entries which are never used."+
+				" The program exits with a non-zero status if any"+
+				" problems are found",
+			param.AltNames("lint", "check-only"),
+		)
+
+		ps.Add(paramNameCmpFrom,
+			psetter.Time{
+				Value:  &prog.compareDates.from,
+				Format: paramDateFormat,
+			},
+			"compare the transactions with those on or after this"+
+				" date. The value for each category is shown for both"+
+				" periods together with the change between them."+
+				" Categories which only have transactions in one of the"+
+				" periods are included",
+			param.SeeAlso(paramNameCmpTo, paramNameCmpNamedPeriod,
+				paramNameCmpFiles),
+		)
+
+		ps.Add(paramNameCmpTo,
+			psetter.Time{
+				Value:  &prog.compareDates.to,
+				Format: paramDateFormat,
+			},
+			"compare the transactions with those on or before this"+
+				" date",
+			param.SeeAlso(paramNameCmpFrom, paramNameCmpNamedPeriod,
+				paramNameCmpFiles),
+		)
+
+		ps.Add(paramNameCmpNamedPeriod,
+			psetter.String[string]{
+				Value: &prog.compareNamedPeriod,
+			},
+			"compare the transactions with those in the named period."+
+				" The period is given as for the "+paramNameNamedPeriod+
+				" parameter",
+			param.AltNames("compare-with", "compare-for"),
+			param.SeeAlso(paramNameCmpFrom, paramNameCmpTo,
+				paramNameNamedPeriod),
+		)
+
+		ps.AddFinalCheck(func() error {
+			if prog.compareNamedPeriod == "" {
+				return prog.compareDates.check()
+			}
+
+			if prog.compareDates.isSet() {
+				return errors.New("the " + paramNameCmpNamedPeriod +
+					" parameter cannot be given with either the " +
+					paramNameCmpFrom + " or the " + paramNameCmpTo +
+					" parameter")
+			}
+
+			var err error
+
+			prog.compareDates, err = parseNamedPeriod(prog.compareNamedPeriod)
+
+			return err
+		})
+
+		ps.Add(paramNameCmpFiles,
+			psetter.PathnameListAppender{
+				Value:       &prog.compareFiles,
+				Expectation: filecheck.FileExists(),
+			},
+			"compare the transactions with those in these bank"+
+				" account files. If no comparison dates are given all"+
+				" the transactions in these files are used",
+			param.AltNames("compare-file"),
+			param.SeeAlso(paramNameCmpFrom, paramNameCmpNamedPeriod),
+		)
+
+		ps.Add(paramNameStore,
+			psetter.Pathname{
+				Value: &prog.storeFileName,
+			},
+			"the name of the file in which transactions are stored."+
+				" Each line holds one transaction, in JSON form,"+
+				" together with the file it was read from. The"+
+				" transactions in any bank account files given are"+
+				" added to those in the store before reporting, so the"+
+				" account files need not be given at all. The"+
+				" transactions are filtered by the dates given in the"+
+				" usual way",
+			param.AltNames("store-file", "xactn-store"),
+			param.SeeAlso(paramNameImport, paramNameFrom,
+				paramNameNamedPeriod),
+		)
+
+		ps.Add(paramNameImport,
+			psetter.Bool{Value: &prog.importToStore},
+			"add the transactions from the bank account files to the"+
+				" "+paramNameStore+" rather than report them. Only"+
+				" those transactions which are not already in the"+
+				" store are added so importing an export which"+
+				" overlaps with earlier ones will not create duplicate"+
+				" entries. The store is created if it does not exist",
+			param.SeeAlso(paramNameStore),
+		)
+
+		ps.AddFinalCheck(func() error {
+			if prog.importToStore && prog.storeFileName == "" {
+				return errors.New("the " + paramNameImport +
+					" parameter needs the " + paramNameStore +
+					" parameter to be given")
+			}
+
+			return nil
+		})
+
+		ps.Add("output-format",
+			psetter.Enum[string]{
+				Value: &prog.outputFormat,
+				AllowedVals: psetter.AllowedVals[string]{
+					outText: "aligned columns of text",
+					outCSV: "comma-separated values with a header line." +
+						" Each row gives the category, its parent" +
+						" and its depth in the tree",
+					outJSON: "a JSON array with an entry for each" +
+						" category shown, each entry holds the tree of" +
+						" its sub-categories",
+					outMarkdown: "a Markdown table for each category shown",
+				},
+			},
+			"the format in which to write the report",
+			param.AltNames("out-fmt", "format-out"),
+			param.SeeAlso("output-file"),
+		)
+
+		ps.Add("output-file",
+			psetter.Pathname{
+				Value:       &prog.outputFileName,
+				Expectation: filecheck.IsNew(),
+			},
+			"the name of the file to which the report should be"+
+				" written. The file must not already exist. If this"+
+				" is not given the report is written to the standard"+
+				" output",
+			param.AltNames("out"),
+			param.SeeAlso("output-format"),
+		)
+
+		ps.AddFinalCheck(prog.checkReports)
+
+		ps.Add(paramNameCurrency,
+			psetter.String[string]{
+				Value: &prog.currency,
+				Checks: []check.String{
+					check.StringLength[string](check.ValGT(0)),
+				},
+			},
+			"the currency of the bank account files. This is used for"+
+				" any file which does not give the currency itself"+
+				" and which is not matched by a "+paramNameFileCurrency+
+				" pattern",
+			param.AltNames("ccy"),
+			param.SeeAlso(paramNameFileCurrency, paramNameRptCurrency),
+		)
+
+		ps.Add(paramNameFileCurrency,
+			psetter.StrListAppender[string]{
+				Value: &prog.fileCurrencies,
+			},
+			"give the currency of those bank account files whose names"+
+				" match the pattern. The value should be a file name"+
+				" pattern and a currency separated by '=', for instance:"+
+				"\n\n"+
+				"*-euro.csv=EUR\n\n"+
+				"The pattern is matched against both the full name and"+
+				" the base name of the file and the first matching"+
+				" pattern is used. A currency given in the file itself"+
+				" (an OFX CURDEF or a "+bankac.FieldCurrency+" column in a"+
+				" CSV layout) takes precedence",
+			param.AltNames("file-ccy"),
+			param.SeeAlso(paramNameCurrency),
+		)
+
+		ps.AddFinalCheck(func() error {
+			for _, fc := range prog.fileCurrencies {
+				if _, _, err := parseFileCurrency(fc); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+
+		ps.Add(paramNameRptCurrency,
+			psetter.String[string]{
+				Value: &prog.reportCurrency,
+				Checks: []check.String{
+					check.StringLength[string](check.ValGT(0)),
+				},
+			},
+			"the currency in which the amounts are reported. Any"+
+				" transactions in other currencies are converted using"+
+				" the rates in the "+paramNameRatesFile+". If this is"+
+				" not given the "+paramNameCurrency+" is used",
+			param.AltNames("rpt-ccy"),
+			param.SeeAlso(paramNameRatesFile, paramNameCurrency),
+		)
+
+		ps.AddFinalCheck(func() error {
+			prog.currency = strings.ToUpper(prog.currency)
+			prog.reportCurrency = strings.ToUpper(prog.reportCurrency)
+
+			if prog.reportCurrency == "" {
+				prog.reportCurrency = prog.currency
+			}
+
+			return nil
+		})
+
+		ps.Add(paramNameRatesFile,
+			psetter.Pathname{
+				Value:       &prog.ratesFileName,
+				Expectation: filecheck.FileExists(),
+			},
+			"the name of the file containing the exchange rates."+
+				" Each line should give the date from which the rate"+
+				" applies (in the form YYYY-MM-DD), the currency"+
+				" being converted from, the currency being converted"+
+				" to and the amount of the second currency that one"+
+				" unit of the first will buy. For instance:\n\n"+
+				"2024-01-01 EUR GBP 0.86\n\n"+
+				"A transaction is converted using the most recent rate"+
+				" on or before its date. If there is no rate in the"+
+				" direction needed the inverse of the rate in the other"+
+				" direction is used. Blank lines and lines starting"+
+				" with '#' are ignored",
+			param.AltNames("exchange-rates"),
+			param.SeeAlso(paramNameRptCurrency),
+		)
+
+		ps.Add("show-original-currency",
+			psetter.Bool{Value: &prog.showOrigCcy},
+			"show the nett amounts in each of the original currencies"+
+				" as well as in the reporting currency. In CSV output"+
+				" there is a column for each currency",
+			param.AltNames("show-orig-ccy"),
+			param.SeeAlso(paramNameRptCurrency),
+		)
+
+		ps.Add(paramNameOwnAccount,
+			psetter.StrListAppender[string]{
+				Value: &prog.ownAccountDefs,
+			},
+			"name one of our own accounts. Any debit in one of these"+
+				" accounts which is matched by a credit of the same"+
+				" amount in another, within a few days, is taken as a"+
+				" transfer between the accounts rather than as spending"+
+				" or income. The value should be the name of the"+
+				" account and the sort-code and account number, as"+
+				" given in the bank account files, separated by '='."+
+				" For instance:\n\n"+
+				"savings=11-22-33 87654321\n\n"+
+				"Transactions whose description mentions the name or"+
+				" the account number of another of our accounts but"+
+				" which have not been matched are reported",
+			param.AltNames("own-ac"),
+			param.SeeAlso(paramNameTransfers, paramNameTransferDays),
+		)
+
+		ps.AddFinalCheck(prog.setOwnAccounts)
+
+		ps.Add(paramNameTransfers,
+			psetter.Enum[string]{
+				Value: &prog.transfers,
+				AllowedVals: psetter.AllowedVals[string]{
+					transfersCategorise: "put the transfers in the '" +
+						bankac.CatTransfers + "' category",
+					transfersExclude: "leave the transfers out of" +
+						" the report",
+				},
+			},
+			"what to do with the transfers between our own accounts",
+			param.SeeAlso(paramNameOwnAccount),
+		)
+
+		ps.Add(paramNameTransferDays,
+			psetter.Int[int]{
+				Value:  &prog.transferDays,
+				Checks: []check.ValCk[int]{check.ValGE(0)},
+			},
+			"the maximum number of days between the two halves of a"+
+				" transfer between our own accounts",
+			param.SeeAlso(paramNameOwnAccount),
+		)
+
 		ps.Add("minimal-amount",
 			psetter.Float[float64]{Value: &prog.minimalAmount},
 			"don't show summaries where the total transactions are"+
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nickwells/col.mod/v6/col"
+	"github.com/nickwells/col.mod/v6/colfmt"
+	"github.com/nickwells/personal-utils/bankACAnalysis/bankac"
+)
+
+const (
+	dfltAnomalyFactor = 3.0
+	dfltNewPayeeMin   = 100.0
+
+	// anomalyMinCount is the smallest number of transactions in a category
+	// from which its usual range of amounts is found
+	anomalyMinCount = 5
+
+	// madScale scales the median absolute deviation so that it estimates
+	// the standard deviation of normally distributed values
+	madScale = 1.4826
+
+	// newPayeeGraceDays is the number of days from the first transaction
+	// during which payees are not taken as new, every payee is new at the
+	// start of the data
+	newPayeeGraceDays = 30
+
+	// usualDayMargin is the number of days either side of the usual day of
+	// the month on which a payment is still taken as being on the usual day
+	usualDayMargin = 2
+)
+
+// anomalyKey identifies a group of transactions from which the usual range
+// of amounts is found
+type anomalyKey struct {
+	category string
+	credit   bool
+}
+
+// usualRange returns the largest amount which is not out of character for
+// the amounts. This is the median plus the factor times the (scaled)
+// median absolute deviation or, if there is no deviation, twice the
+// median.
+func usualRange(amts []float64, factor float64) float64 {
+	mid := median(amts)
+
+	devs := make([]float64, 0, len(amts))
+	for _, amt := range amts {
+		devs = append(devs, math.Abs(amt-mid))
+	}
+
+	const noDevFactor = 2
+
+	mad := median(devs) * madScale
+	if mad < balanceTolerance {
+		return noDevFactor * mid
+	}
+
+	return mid + factor*mad
+}
+
+// dayDistance returns the number of days between the two days of the
+// month, allowing for the end of one month running into the next
+func dayDistance(a, b int) int {
+	const daysPerMonth = 31
+
+	d := a - b
+	if d < 0 {
+		d = -d
+	}
+
+	return min(d, daysPerMonth-d)
+}
+
+// usualDay returns the day of the month on which most of the payments are
+// made. It returns false if there is no such day.
+func usualDay(xas []Xactn) (int, bool) {
+	days := make([]float64, 0, len(xas))
+	for _, xa := range xas {
+		days = append(days, float64(xa.Date.Day()))
+	}
+
+	day := int(median(days))
+	onDay := 0
+
+	for _, xa := range xas {
+		if dayDistance(xa.Date.Day(), day) <= usualDayMargin {
+			onDay++
+		}
+	}
+
+	return day, float64(onDay)/float64(len(xas)) >= recurringIntervalShare
+}
+
+// anomalies returns the reasons why each of the transactions in the
+// category looks out of character, keyed by the location of the
+// transaction. The transactions are also returned in date order.
+func (s *summaries) anomalies(
+	prog *prog, cat string,
+) ([]Xactn, map[string][]string) {
+	xas := bankac.MergeParts(s.categoryXactns(cat, listByDate))
+	reasons := map[string][]string{}
+
+	flag := func(xa Xactn, format string, args ...any) {
+		reasons[xa.Location()] = append(reasons[xa.Location()],
+			fmt.Sprintf(format, args...))
+	}
+
+	amts := map[anomalyKey][]float64{}
+	byDesc := map[string][]Xactn{}
+
+	for _, xa := range xas {
+		if xa.IsTransfer {
+			continue
+		}
+
+		k := anomalyKey{
+			category: s.CategoryOf(xa),
+			credit:   xa.CreditAmt > 0,
+		}
+		amts[k] = append(amts[k], amountOf(xa, k.credit))
+
+		if xa.DebitAmt > 0 {
+			byDesc[xa.Desc] = append(byDesc[xa.Desc], xa)
+		}
+	}
+
+	limits := map[anomalyKey]float64{}
+
+	for k, a := range amts {
+		if len(a) >= anomalyMinCount {
+			limits[k] = usualRange(a, prog.anomalyFactor)
+		}
+	}
+
+	for _, xa := range xas {
+		k := anomalyKey{
+			category: s.CategoryOf(xa),
+			credit:   xa.CreditAmt > 0,
+		}
+
+		if limit, ok := limits[k]; ok && !xa.IsTransfer &&
+			amountOf(xa, k.credit) > limit {
+			flag(xa, "the amount is above the usual range for %q (%.2f)",
+				k.category, limit)
+		}
+	}
+
+	if len(xas) > 0 {
+		prog.checkPayees(xas[0].Date, byDesc, flag)
+	}
+
+	return xas, reasons
+}
+
+// checkPayees flags the first payment to a new payee if it is above the
+// minimum, any payment made more than once on the same day for the same
+// amount and any payment made on an unusual day of the month
+func (prog *prog) checkPayees(
+	firstDate time.Time,
+	byDesc map[string][]Xactn,
+	flag func(Xactn, string, ...any),
+) {
+	newAfter := firstDate.AddDate(0, 0, newPayeeGraceDays)
+
+	for desc, xas := range byDesc {
+		first := xas[0]
+		if first.Date.After(newAfter) && first.DebitAmt >= prog.newPayeeMin {
+			flag(first, "the first payment to %q", desc)
+		}
+
+		type payment struct {
+			date string
+			amt  float64
+		}
+
+		seen := map[payment]string{}
+
+		for _, xa := range xas {
+			p := payment{
+				date: xa.Date.Format(paramDateFormat),
+				amt:  roundAmt(xa.DebitAmt),
+			}
+
+			if loc, ok := seen[p]; ok {
+				flag(xa, "a duplicate of the payment at %s", loc)
+				continue
+			}
+
+			seen[p] = xa.Location()
+		}
+
+		if len(xas) < prog.recurringMinCount {
+			continue
+		}
+
+		day, ok := usualDay(xas)
+		if !ok {
+			continue
+		}
+
+		for _, xa := range xas {
+			if dayDistance(xa.Date.Day(), day) > usualDayMargin {
+				flag(xa, "paid on day %d, usually paid on day %d",
+					xa.Date.Day(), day)
+			}
+		}
+	}
+}
+
+// anomalyReport will list the transactions in the category which look out
+// of character, showing where they were found and the reasons why
+func (s *summaries) anomalyReport(prog *prog, cat string) {
+	const (
+		floatColWidth = 10
+		floatColPrec  = 2
+	)
+
+	if s.Summary(cat) == nil {
+		fmt.Fprintf(os.Stderr, "*** category: %q is not recognised\n", cat)
+		return
+	}
+
+	xas, reasons := s.anomalies(prog, cat)
+
+	locWidth := len("Location")
+	descWidth := len("Description")
+	reasonWidth := len("Reason")
+
+	for _, xa := range xas {
+		r, ok := reasons[xa.Location()]
+		if !ok {
+			continue
+		}
+
+		locWidth = max(locWidth, len(xa.Location()))
+		descWidth = max(descWidth, len(xa.Desc))
+		reasonWidth = max(reasonWidth, len(strings.Join(r, "; ")))
+	}
+
+	floatCol := colfmt.Float{
+		W:    floatColWidth,
+		Prec: floatColPrec,
+		Zeroes: &colfmt.FloatZeroHandler{
+			Handle:  true,
+			Replace: "",
+		},
+	}
+
+	rpt := col.NewReportOrPanic(col.NewHeaderOrPanic(), prog.out,
+		col.New(&colfmt.Time{Format: rptDateFormat}, "Date"),
+		col.New(&colfmt.String{W: locWidth}, "Location"),
+		col.New(&colfmt.String{W: descWidth}, "Description"),
+		col.New(&floatCol, "Debit", "Amount"),
+		col.New(&floatCol, "Credit", "Amount"),
+		col.New(&colfmt.String{W: reasonWidth}, "Reason"),
+	)
+
+	for _, xa := range xas {
+		r, ok := reasons[xa.Location()]
+		if !ok {
+			continue
+		}
+
+		err := rpt.PrintRow(
+			xa.Date,
+			xa.Location(),
+			xa.Desc,
+			xa.DebitAmt,
+			xa.CreditAmt,
+			strings.Join(r, "; "))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Couldn't print the row:", err)
+		}
+	}
+}
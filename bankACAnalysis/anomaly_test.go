@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestUsualRange(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		amts     []float64
+		expLimit float64
+	}{
+		{
+			ID:       testhelper.MkID("no spread"),
+			amts:     []float64{10, 10, 10, 10, 50},
+			expLimit: 20,
+		},
+		{
+			ID:       testhelper.MkID("spread"),
+			amts:     []float64{19, 20, 21, 22, 250},
+			expLimit: 21 + 3*madScale,
+		},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffFloat(t, tc.IDStr(), "limit",
+			usualRange(tc.amts, dfltAnomalyFactor), tc.expLimit, 1e-9)
+	}
+}
+
+func TestDayDistance(t *testing.T) {
+	testhelper.DiffInt(t, "same month", "distance", dayDistance(5, 8), 3)
+	testhelper.DiffInt(t, "across months", "distance", dayDistance(30, 1), 2)
+}
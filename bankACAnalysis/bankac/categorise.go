@@ -0,0 +1,229 @@
+package bankac
+
+import (
+	"fmt"
+	"math"
+)
+
+// CatTransfers is the category holding the transfers between our own
+// accounts
+const CatTransfers = "transfers"
+
+const (
+	// amountTolerance is the largest difference between two amounts which
+	// are taken to be the same
+	amountTolerance = 0.005
+
+	hoursPerDay = 24
+
+	// keyDateFormat is the layout of the dates identifying individual
+	// transactions in the split override and tag files
+	keyDateFormat = "2006-01-02"
+)
+
+// roundAmt rounds the amount to the nearest penny
+func roundAmt(amt float64) float64 {
+	const pennies = 100
+
+	return math.Round(amt*pennies) / pennies
+}
+
+// Categorised represents a single transaction, as read from the file,
+// together with the details recorded as it is categorised
+type Categorised struct {
+	Xactn
+
+	// OrigDesc is the description as given in the file, before any edits
+	// have been made, and EditedBy holds the indexes of the edits which
+	// changed it
+	OrigDesc string
+	EditedBy []int
+
+	// Tags holds the tags given to the transaction, they are independent
+	// of its category
+	Tags []string
+
+	// IsTransfer is set if the transaction is one half of a transfer
+	// between our own accounts
+	IsTransfer bool
+
+	// SummName is the name of the Summary record to which the transaction
+	// is added
+	SummName string
+}
+
+// Categoriser places the transactions in the tree of categories and adds
+// them to the Summary records. The descriptions are normalised with the
+// edits and the transactions are tagged, split, and categorised by the
+// rules, the map or their type. The zero value is not ready to use,
+// NewCategoriser should be called to create a Categoriser.
+type Categoriser struct {
+	*Summaries
+
+	// Edits are applied to the descriptions which are not in the tree
+	Edits Edits
+
+	rules    []Rule
+	tagRules []Rule
+
+	// the splits of transactions by description and the split overrides
+	// of individual transactions
+	splits         map[string]*split
+	splitOverrides map[dateAmtKey]*split
+
+	// the tags from the tag file for descriptions and for individual
+	// transactions
+	descTags  map[string][]string
+	xactnTags map[dateAmtKey][]string
+
+	// Edited, if it is not nil, is called for each transaction whose
+	// description has been normalised, with the result of the edits
+	Edited func(xa Categorised, n Normalised)
+
+	// Note, if it is not nil, is called with a description of each rule
+	// or split applied to a transaction
+	Note func(msg string)
+
+	// Problem, if it is not nil, is called with the error for each
+	// transaction which cannot be put in its category or summarised
+	Problem func(err error)
+}
+
+// NewCategoriser returns a Categoriser adding the transactions to the
+// Summaries
+func NewCategoriser(s *Summaries) *Categoriser {
+	return &Categoriser{
+		Summaries:      s,
+		splits:         map[string]*split{},
+		splitOverrides: map[dateAmtKey]*split{},
+		descTags:       map[string][]string{},
+		xactnTags:      map[dateAmtKey][]string{},
+	}
+}
+
+// note reports how the transaction has been categorised
+func (c *Categoriser) note(xa Categorised, format string, args ...any) {
+	if c.Note != nil {
+		c.Note(fmt.Sprintf("%s: %q ", xa.Location(), xa.Desc) +
+			fmt.Sprintf(format, args...))
+	}
+}
+
+// problem reports the error found with the transaction
+func (c *Categoriser) problem(xa Categorised, format string, args ...any) {
+	if c.Problem != nil {
+		c.Problem(fmt.Errorf("%s: %s",
+			xa.Location(), fmt.Sprintf(format, args...)))
+	}
+}
+
+// Categorise normalises the transaction description, if it is not already
+// in the tree, and then adds the transaction to the Summary records.
+// Transfers between our own accounts are put in their own category.
+// Transactions to be split are spread over the categories of the split.
+// Otherwise the categorisation rules are tried first and only if none of
+// them match is the map used. It returns the transactions added, one for
+// each part of a split transaction.
+func (c *Categoriser) Categorise(xa Categorised) []Categorised {
+	xa.OrigDesc = xa.Desc
+
+	if _, ok := c.Tree().Parent(xa.Desc); !ok {
+		n := c.Edits.Normalise(xa.Desc)
+		xa.Desc, xa.EditedBy = n.Desc, n.Changed
+
+		if c.Edited != nil {
+			c.Edited(xa, n)
+		}
+	}
+
+	xa.SummName = xa.Desc
+
+	c.tagXactn(&xa)
+
+	if xa.IsTransfer {
+		if err := c.setCategory(CatTransfers, &xa); err != nil {
+			c.problem(xa, "Can't add the transfer to the %s: %s",
+				mapDesc, err)
+		}
+	} else if sp := c.findSplit(xa); sp != nil {
+		return c.addSplitXactn(sp, xa)
+	} else if r := c.matchRule(xa); r != nil {
+		c.note(xa, "matches the rule at %s: category: %s", r, r.category)
+		c.applyRule(r, &xa)
+	} else {
+		c.addMapEntry(xa)
+	}
+
+	c.summarise(xa, nil)
+
+	return []Categorised{xa}
+}
+
+// CategoryOf returns the category of the transaction, that is, the parent
+// of the entry to which it is added
+func (c *Categoriser) CategoryOf(xa Categorised) string {
+	parent, _ := c.Tree().Parent(xa.SummName)
+	return parent
+}
+
+// summarise will summarise the transaction working its way up to the top
+// of the tree of Summary records. The counted map records the Summary
+// records which have already counted the transaction, it is nil unless the
+// transaction is part of a split transaction.
+func (c *Categoriser) summarise(
+	xa Categorised, counted map[*Summary]bool,
+) {
+	if err := c.Add(xa.SummName, xa.Xactn, counted); err != nil {
+		c.problem(xa, "Couldn't summarise the transaction: %s", err)
+	}
+}
+
+// setCategory puts the transaction in the category. If the description
+// already has a different parent then the transaction is summarised under
+// a name made from the category and the description.
+func (c *Categoriser) setCategory(cat string, xa *Categorised) error {
+	if parent, ok := c.Tree().Parent(xa.SummName); ok && parent != cat {
+		xa.SummName = cat + ": " + xa.Desc
+	}
+
+	return c.AddParent(cat, xa.SummName)
+}
+
+// addMapEntry will create a new parent/child entry in the tree for the
+// transaction if it is not already known or if it is a cheque or cashpoint
+// withdrawal. An unknown description which matches one of the patterns in
+// the map is put in the pattern's category.
+func (c *Categoriser) addMapEntry(xa Categorised) {
+	switch xa.Type {
+	case XaTypeCheque:
+		if err := c.AddParent(CatCheque, xa.Desc); err != nil {
+			c.problem(xa, "Can't add the cheque to the %s: %s",
+				mapDesc, err)
+		}
+	case XaTypeCash:
+		if err := c.AddParent(CatCash, xa.Desc); err != nil {
+			c.problem(xa,
+				"Can't add the cashpoint withdrawal to the %s: %s",
+				mapDesc, err)
+		}
+	default:
+		if _, ok := c.Tree().Parent(xa.Desc); ok {
+			return
+		}
+
+		if cat, ok := c.Tree().PatternCategory(xa.Desc); ok {
+			if err := c.AddParent(cat, xa.Desc); err != nil {
+				c.problem(xa,
+					"Can't add the entry matching a pattern to the %s: %s",
+					mapDesc, err)
+			}
+
+			return
+		}
+
+		if err := c.AddParent(CatUnknown, xa.Desc); err != nil {
+			c.problem(xa, "Can't add the unknown entry to the %s: %s",
+				mapDesc, err)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package bankac
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+// mkCategoriser returns a Categoriser with the map, edits, rules, splits
+// and tags used to test the categorisation
+func mkCategoriser(t *testing.T) *Categoriser {
+	t.Helper()
+
+	tree := NewTree()
+	if err := tree.AddParent(CatAll, CatTransfers); err != nil {
+		t.Fatal("couldn't add the transfers category:", err)
+	}
+
+	errs := tree.ReadNestedMap("map", strings.NewReader(
+		"food:\n"+
+			"    - TESCO\n"+
+			"    ~ ^LIDL\n"+
+			"bills:\n"+
+			"    - GAS CO\n"+
+			"household:\n"))
+
+	c := NewCategoriser(NewSummaries(tree))
+
+	var edErrs []error
+
+	c.Edits, edErrs = ReadEdits("edits", strings.NewReader(
+		"search=^TESCO.*\n"+
+			"replace=TESCO\n"))
+	errs = append(errs, edErrs...)
+	errs = append(errs, c.ReadRules("rules", strings.NewReader(
+		"desc=^(GAS|AMAZON)\n"+
+			"category=household\n"+
+			"amount=100:\n"+
+			"tag=big\n"))...)
+	errs = append(errs, c.ReadSplits("splits", strings.NewReader(
+		"COSTCO = food:50%, household:50%\n"))...)
+	errs = append(errs, c.ReadSplitOverrides("overrides", strings.NewReader(
+		"2024-03-09 20 = food:5, household:15\n"))...)
+	errs = append(errs, c.ReadTags("tags", strings.NewReader(
+		"TESCO = groceries\n"))...)
+
+	if len(errs) != 0 {
+		t.Fatal("unexpected errors:", errs)
+	}
+
+	return c
+}
+
+func TestCategorise(t *testing.T) {
+	type expPart struct {
+		desc     string
+		summName string
+		category string
+		debitAmt float64
+		tags     []string
+	}
+
+	testCases := []struct {
+		testhelper.ID
+		xa       Xactn
+		transfer bool
+		expParts []expPart
+		expNotes int
+	}{
+		{
+			ID: testhelper.MkID("edited to an entry in the map"),
+			xa: Xactn{Desc: "TESCO EXPRESS", DebitAmt: 10},
+			expParts: []expPart{
+				{"TESCO", "TESCO", "food", 10, []string{"groceries"}},
+			},
+		},
+		{
+			ID: testhelper.MkID("matches a pattern in the map"),
+			xa: Xactn{Desc: "LIDL STORE", DebitAmt: 10},
+			expParts: []expPart{
+				{"LIDL STORE", "LIDL STORE", "food", 10, nil},
+			},
+		},
+		{
+			ID: testhelper.MkID("a rule takes precedence over the map"),
+			xa: Xactn{Desc: "GAS CO", DebitAmt: 40},
+			expParts: []expPart{
+				{"GAS CO", "household: GAS CO", "household", 40, nil},
+			},
+			expNotes: 1,
+		},
+		{
+			ID: testhelper.MkID("a rule and a tag rule"),
+			xa: Xactn{Desc: "AMAZON", DebitAmt: 150},
+			expParts: []expPart{
+				{"AMAZON", "AMAZON", "household", 150, []string{"big"}},
+			},
+			expNotes: 1,
+		},
+		{
+			ID: testhelper.MkID("split by description"),
+			xa: Xactn{Desc: "COSTCO", DebitAmt: 20},
+			expParts: []expPart{
+				{"COSTCO", "COSTCO", "food", 10, nil},
+				{"COSTCO", "household: COSTCO", "household", 10, nil},
+			},
+			expNotes: 1,
+		},
+		{
+			ID: testhelper.MkID("a split override takes precedence"),
+			xa: Xactn{Desc: "COSTCO", DebitAmt: 20, Date: mkDate(2024, 3, 9)},
+			expParts: []expPart{
+				{"COSTCO", "COSTCO", "food", 5, nil},
+				{"COSTCO", "household: COSTCO", "household", 15, nil},
+			},
+			expNotes: 1,
+		},
+		{
+			ID:       testhelper.MkID("a transfer is not split"),
+			xa:       Xactn{Desc: "COSTCO", DebitAmt: 20},
+			transfer: true,
+			expParts: []expPart{
+				{"COSTCO", "COSTCO", CatTransfers, 20, nil},
+			},
+		},
+		{
+			ID: testhelper.MkID("cheque"),
+			xa: Xactn{Desc: "100123", Type: XaTypeCheque, DebitAmt: 5},
+			expParts: []expPart{
+				{"100123", "100123", CatCheque, 5, nil},
+			},
+		},
+		{
+			ID: testhelper.MkID("cashpoint"),
+			xa: Xactn{Desc: "ATM", Type: XaTypeCash, DebitAmt: 5},
+			expParts: []expPart{
+				{"ATM", "ATM", CatCash, 5, nil},
+			},
+		},
+		{
+			ID: testhelper.MkID("unknown"),
+			xa: Xactn{Desc: "NEWSAGENT", DebitAmt: 5},
+			expParts: []expPart{
+				{"NEWSAGENT", "NEWSAGENT", CatUnknown, 5, nil},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		c := mkCategoriser(t)
+
+		notes := 0
+		c.Note = func(string) { notes++ }
+		c.Problem = func(err error) {
+			t.Log(tc.IDStr())
+			t.Error("\t: unexpected problem:", err)
+		}
+
+		if tc.xa.Date.IsZero() {
+			tc.xa.Date = mkDate(2024, time.March, 1)
+		}
+
+		parts := c.Categorise(
+			Categorised{Xactn: tc.xa, IsTransfer: tc.transfer})
+
+		gotParts := []expPart{}
+		for _, p := range parts {
+			gotParts = append(gotParts, expPart{
+				p.Desc, p.SummName, c.CategoryOf(p), p.DebitAmt, p.Tags,
+			})
+
+			testhelper.DiffString(t, tc.IDStr(), "original description",
+				p.OrigDesc, tc.xa.Desc)
+		}
+
+		testhelper.DiffValsReport(t, tc.IDStr(), "parts",
+			gotParts, tc.expParts)
+		testhelper.DiffInt(t, tc.IDStr(), "notes", notes, tc.expNotes)
+
+		all := c.Summary(CatAll)
+		testhelper.DiffInt(t, tc.IDStr(), "count", all.Count, 1)
+		testhelper.DiffFloat(t, tc.IDStr(), "total debit",
+			all.DebitAmt, tc.xa.DebitAmt, 1e-9)
+	}
+}
+
+func TestCategoriseEdited(t *testing.T) {
+	c := mkCategoriser(t)
+
+	var edited []Normalised
+
+	c.Edited = func(_ Categorised, n Normalised) {
+		edited = append(edited, n)
+	}
+
+	for _, desc := range []string{"TESCO", "TESCO METRO", "NEWSAGENT"} {
+		c.Categorise(Categorised{Xactn: Xactn{Desc: desc, DebitAmt: 1}})
+	}
+
+	testhelper.DiffValsReport(t, "edited", "normalised", edited,
+		[]Normalised{
+			{Desc: "TESCO", Matched: []int{0}, Changed: []int{0}},
+			{Desc: "NEWSAGENT"},
+		})
+}
@@ -0,0 +1,138 @@
+package bankac
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReadCSV reads the transactions from the comma-separated values in the
+// io.Reader. Lines which cannot be converted into transactions are passed
+// to BadEntry and skipped.
+func (rd *Reader) ReadCSV(name string, r io.Reader) ([]Xactn, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1 // the layout checks for missing columns
+
+	lineNum := 0
+
+	var cm colMap
+
+	if !rd.SkipFirstLine {
+		var err error
+
+		cm, err = rd.Layout.resolve(nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	xas := []Xactn{}
+
+	for {
+		parts, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		lineNum++
+		if rd.SkipFirstLine && lineNum == 1 {
+			cm, err = rd.Layout.resolve(parts)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", name, lineNum, err)
+			}
+
+			continue // the first line of headings holds no transaction
+		}
+
+		xa, err := rd.mkXactn(cm, parts)
+		if err != nil {
+			rd.badEntry(name, lineNum, err)
+			continue
+		}
+
+		xa.FileName = name
+		xa.LineNum = lineNum
+		xas = append(xas, xa)
+	}
+
+	return xas, nil
+}
+
+// parseNum returns 0.0 if the string is empty, otherwise it will parse the
+// number as a float
+func parseNum(s, name string) (float64, error) {
+	if s == "" {
+		return 0.0, nil
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0.0, fmt.Errorf("couldn't parse the %s: %s", name, err)
+	}
+
+	return n, nil
+}
+
+// mkXactn converts the slice of strings into an transaction record using
+// the column map to find the values
+func (rd *Reader) mkXactn(cm colMap, parts []string) (Xactn, error) {
+	vals := map[string]string{}
+
+	for field := range cm {
+		v, err := cm.get(parts, field)
+		if err != nil {
+			return Xactn{}, err
+		}
+
+		vals[field] = v
+	}
+
+	date, err := time.Parse(rd.Layout.dateFormat, vals[FieldDate])
+	if err != nil {
+		return Xactn{}, fmt.Errorf("couldn't parse the date: %s", err)
+	}
+
+	xa := Xactn{
+		Date: date,
+		Type: vals[FieldType],
+		Desc: vals[FieldDesc],
+	}
+
+	if cm.has(FieldAmount) {
+		amt, err := parseNum(vals[FieldAmount], "amount")
+		if err != nil {
+			return Xactn{}, err
+		}
+
+		xa.SetAmount(amt)
+	} else {
+		xa.DebitAmt, err = parseNum(vals[FieldDebit], "debit amount")
+		if err != nil {
+			return Xactn{}, err
+		}
+
+		xa.CreditAmt, err = parseNum(vals[FieldCredit], "credit amount")
+		if err != nil {
+			return Xactn{}, err
+		}
+	}
+
+	xa.Balance, err = parseNum(vals[FieldBalance], "balance amount")
+	if err != nil {
+		return Xactn{}, err
+	}
+
+	xa.HasBalance = vals[FieldBalance] != ""
+	xa.Currency = strings.ToUpper(vals[FieldCurrency])
+	xa.Account = strings.TrimSpace(
+		vals[FieldSortCode] + " " + vals[FieldAccount])
+
+	return xa, nil
+}
@@ -0,0 +1,18 @@
+/*
+Package bankac implements the reading of bank account statements, the editing
+of the transaction descriptions into a normal form, the tree of categories
+into which the transactions are arranged and the summaries of the
+transactions in each category. It is used by the bankACAnalysis command but
+can be used by any program that wants to make sense of a bank statement.
+
+A Categoriser places each transaction in the tree. Transfers between our own
+accounts go in their own category, then any split of the transaction is
+applied, then the categorisation rules are tried and only if none of them
+match is the map used. The transactions are tagged from the tag file and by
+the tag rules.
+
+The statements can be read from comma-separated values (CSV), OFX (or QFX)
+and QIF files. Problems are returned as errors rather than being reported so
+the caller can decide what to do about them.
+*/
+package bankac
@@ -0,0 +1,147 @@
+package bankac
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// The types of the entries in the edit file
+const (
+	EditTypeSearch  = "search"
+	EditTypeReplace = "replace"
+)
+
+const editErrIntro = "Bad transaction edits entry"
+
+// Edit represents a substitution to be made to a transaction description
+type Edit struct {
+	LineNum     int
+	Search      string
+	SearchRE    *regexp.Regexp
+	Replacement string
+}
+
+// Edits holds the edits to be made to the transaction descriptions in the
+// order in which they are applied
+type Edits []Edit
+
+// ReadEdits reads the edits from the io.Reader. Each edit is given as a
+// pair of lines, the first starting with 'search=' and giving a regular
+// expression and the second starting with 'replace=' and giving the text to
+// replace any match. Bad entries are skipped and an error is returned for
+// each one.
+func ReadEdits(name string, r io.Reader) (Edits, []error) {
+	eScanner := bufio.NewScanner(r)
+	lineNum := 0
+	prevType := ""
+	eds := Edits{}
+	errs := []error{}
+
+	var (
+		searchRE   *regexp.Regexp
+		searchStr  string
+		searchLine int
+		errFound   bool
+		err        error
+	)
+
+	badEntry := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s:%d: %s: %s",
+			name, lineNum, editErrIntro, fmt.Sprintf(format, args...)))
+	}
+
+	for eScanner.Scan() {
+		lineNum++
+
+		line := eScanner.Text()
+		if line == "" {
+			continue
+		}
+
+		entryType, entryValue, ok := strings.Cut(line, "=")
+		if !ok {
+			badEntry("missing '=': %s", line)
+
+			errFound = true
+
+			continue
+		}
+
+		switch entryType {
+		case EditTypeSearch:
+			if prevType == EditTypeSearch {
+				badEntry("%q entry missing for previous search",
+					EditTypeReplace)
+			}
+
+			errFound = false
+			searchStr = entryValue
+			searchLine = lineNum
+
+			searchRE, err = regexp.Compile(searchStr)
+			if err != nil {
+				badEntry("Couldn't compile the regexp: %s", err)
+
+				errFound = true
+			}
+		case EditTypeReplace:
+			if !errFound {
+				eds = append(eds, Edit{
+					LineNum:     searchLine,
+					Search:      searchStr,
+					SearchRE:    searchRE,
+					Replacement: entryValue,
+				})
+			}
+		default:
+			badEntry("Bad type: %s", entryType)
+
+			errFound = true
+		}
+
+		prevType = entryType
+	}
+
+	if err := eScanner.Err(); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %w", name, err))
+	}
+
+	return eds, errs
+}
+
+// Normalised holds a description after the edits have been applied to it
+// together with the indexes of the edits which matched it and of those
+// which changed it
+type Normalised struct {
+	Desc    string
+	Matched []int
+	Changed []int
+}
+
+// Normalise converts the description into a 'normal' form - this involves
+// editing it to replace multiple alternative spellings into a single
+// variant. Each edit is applied in turn to the description as left by the
+// edits before it.
+func (eds Edits) Normalise(desc string) Normalised {
+	n := Normalised{Desc: desc}
+
+	for i, ed := range eds {
+		if !ed.SearchRE.MatchString(n.Desc) {
+			continue
+		}
+
+		n.Matched = append(n.Matched, i)
+
+		newDesc := ed.SearchRE.ReplaceAllLiteralString(n.Desc, ed.Replacement)
+		if newDesc != n.Desc {
+			n.Changed = append(n.Changed, i)
+		}
+
+		n.Desc = newDesc
+	}
+
+	return n
+}
@@ -0,0 +1,107 @@
+package bankac
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestReadEdits(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		text      string
+		expSearch []string
+		expLines  []int
+		expErrs   int
+	}{
+		{
+			ID: testhelper.MkID("good"),
+			text: "search=^TESCO.*\n" +
+				"replace=TESCO\n" +
+				"\n" +
+				"search=SAINSBURY'?S\n" +
+				"replace=SAINSBURYS\n",
+			expSearch: []string{"^TESCO.*", "SAINSBURY'?S"},
+			expLines:  []int{1, 4},
+		},
+		{
+			ID: testhelper.MkID("bad entries"),
+			text: "search=(\n" +
+				"replace=X\n" +
+				"search=A\n" +
+				"search=B\n" +
+				"replace=C\n" +
+				"nonsense\n" +
+				"find=D\n" +
+				"replace=E\n",
+			expSearch: []string{"B"},
+			expLines:  []int{4},
+			expErrs:   4,
+		},
+	}
+
+	for _, tc := range testCases {
+		eds, errs := ReadEdits("test", strings.NewReader(tc.text))
+
+		search := []string{}
+		lines := []int{}
+
+		for _, ed := range eds {
+			search = append(search, ed.Search)
+			lines = append(lines, ed.LineNum)
+		}
+
+		testhelper.DiffValsReport(t, tc.IDStr(), "searches",
+			search, tc.expSearch)
+		testhelper.DiffValsReport(t, tc.IDStr(), "lines", lines, tc.expLines)
+		testhelper.DiffInt(t, tc.IDStr(), "errors", len(errs), tc.expErrs)
+	}
+}
+
+func TestNormalise(t *testing.T) {
+	eds, errs := ReadEdits("test", strings.NewReader(
+		"search=^TESCO.*\n"+
+			"replace=TESCO\n"+
+			"search=^TESCO STORES\n"+
+			"replace=TESCO\n"+
+			"search=TESCO\n"+
+			"replace=TESCO\n"))
+	if len(errs) != 0 {
+		t.Fatal("unexpected errors:", errs)
+	}
+
+	testCases := []struct {
+		testhelper.ID
+		desc   string
+		expVal Normalised
+	}{
+		{
+			ID:   testhelper.MkID("changed"),
+			desc: "TESCO STORES 1234",
+			expVal: Normalised{
+				Desc:    "TESCO",
+				Matched: []int{0, 2},
+				Changed: []int{0},
+			},
+		},
+		{
+			ID:   testhelper.MkID("matched but unchanged"),
+			desc: "TESCO",
+			expVal: Normalised{
+				Desc:    "TESCO",
+				Matched: []int{0, 2},
+			},
+		},
+		{
+			ID:     testhelper.MkID("no match"),
+			desc:   "LIDL",
+			expVal: Normalised{Desc: "LIDL"},
+		},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffValsReport(t, tc.IDStr(), "normalised",
+			eds.Normalise(tc.desc), tc.expVal)
+	}
+}
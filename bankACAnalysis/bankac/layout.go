@@ -0,0 +1,271 @@
+package bankac
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// The names of the fields that can be given in a CSV layout
+const (
+	FieldDate     = "date"
+	FieldType     = "type"
+	FieldSortCode = "sort-code"
+	FieldAccount  = "account"
+	FieldDesc     = "desc"
+	FieldDebit    = "debit"
+	FieldCredit   = "credit"
+	FieldBalance  = "balance"
+	FieldAmount   = "amount"
+	FieldCurrency = "currency"
+
+	LayoutDateFormat = "date-format"
+
+	DfltLayoutName = "default"
+	DfltDateFormat = "02/01/2006"
+)
+
+// layoutFields lists the fields that can be given in a CSV layout in the
+// order in which they are shown
+var layoutFields = []string{
+	FieldDate,
+	FieldType,
+	FieldSortCode,
+	FieldAccount,
+	FieldDesc,
+	FieldDebit,
+	FieldCredit,
+	FieldBalance,
+	FieldAmount,
+	FieldCurrency,
+}
+
+// CSVLayout describes where the transaction values are to be found in the
+// comma-separated values of a bank account file and how the dates are
+// formatted. A column can be given either as a column number (starting at 1)
+// or as the name of the column in the header line.
+type CSVLayout struct {
+	name       string
+	dateFormat string
+	cols       map[string]string
+}
+
+// colMap maps the field names to the index of the column holding the value
+type colMap map[string]int
+
+// DfltLayout returns the layout of the files as downloaded from the bank
+// that this program was first written for
+func DfltLayout() CSVLayout {
+	return CSVLayout{
+		name:       DfltLayoutName,
+		dateFormat: DfltDateFormat,
+		cols: map[string]string{
+			FieldDate:     "1",
+			FieldType:     "2",
+			FieldSortCode: "3",
+			FieldAccount:  "4",
+			FieldDesc:     "5",
+			FieldDebit:    "6",
+			FieldCredit:   "7",
+			FieldBalance:  "8",
+		},
+	}
+}
+
+// ParseCSVLayout parses the layout definition which should be of the form:
+//
+//	name:field=column,field=column,...
+//
+// The date-format can also be given as if it were a field. A part with no
+// '=' continues the value of the part before it so that a date format or a
+// column name can include a comma.
+func ParseCSVLayout(def string) (CSVLayout, error) {
+	name, fields, ok := strings.Cut(def, ":")
+	if !ok {
+		return CSVLayout{},
+			fmt.Errorf("bad CSV layout %q: there is no ':' after the name",
+				def)
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return CSVLayout{},
+			fmt.Errorf("bad CSV layout %q: the name is missing", def)
+	}
+
+	l := CSVLayout{
+		name:       name,
+		dateFormat: DfltDateFormat,
+		cols:       map[string]string{},
+	}
+
+	parts, err := layoutParts(name, fields)
+	if err != nil {
+		return CSVLayout{}, err
+	}
+
+	for _, part := range parts {
+		field, col, _ := strings.Cut(part, "=")
+		field = strings.TrimSpace(field)
+		col = strings.TrimSpace(col)
+
+		if col == "" {
+			return CSVLayout{},
+				fmt.Errorf("bad CSV layout %q: no column for %q", name, field)
+		}
+
+		if field == LayoutDateFormat {
+			l.dateFormat = col
+			continue
+		}
+
+		if !slices.Contains(layoutFields, field) {
+			return CSVLayout{},
+				fmt.Errorf("bad CSV layout %q: unknown field: %q (%s)",
+					name, field, strings.Join(layoutFields, ", "))
+		}
+
+		if _, ok := l.cols[field]; ok {
+			return CSVLayout{},
+				fmt.Errorf("bad CSV layout %q: field %q is given twice",
+					name, field)
+		}
+
+		l.cols[field] = col
+	}
+
+	return l, l.check()
+}
+
+// layoutParts splits the fields of the layout definition into its
+// field=column parts. A part with no '=' is joined, with the comma, onto the
+// part before it. It is an error if the first part, or an empty part, has
+// no '='.
+func layoutParts(name, fields string) ([]string, error) {
+	parts := []string{}
+
+	for part := range strings.SplitSeq(fields, ",") {
+		if strings.Contains(part, "=") {
+			parts = append(parts, part)
+			continue
+		}
+
+		if len(parts) == 0 || strings.TrimSpace(part) == "" {
+			return nil,
+				fmt.Errorf("bad CSV layout %q: %q has no '='", name, part)
+		}
+
+		parts[len(parts)-1] += "," + part
+	}
+
+	return parts, nil
+}
+
+// check returns a non-nil error if the layout is incomplete or ambiguous
+func (l CSVLayout) check() error {
+	for _, f := range []string{FieldDate, FieldDesc} {
+		if _, ok := l.cols[f]; !ok {
+			return fmt.Errorf("bad CSV layout %q: the %q column must be given",
+				l.name, f)
+		}
+	}
+
+	_, hasAmt := l.cols[FieldAmount]
+	_, hasDebit := l.cols[FieldDebit]
+	_, hasCredit := l.cols[FieldCredit]
+
+	if hasAmt && (hasDebit || hasCredit) {
+		return fmt.Errorf("bad CSV layout %q:"+
+			" give either an %q column or %q and %q columns, not both",
+			l.name, FieldAmount, FieldDebit, FieldCredit)
+	}
+
+	if !hasAmt && !(hasDebit && hasCredit) {
+		return fmt.Errorf("bad CSV layout %q:"+
+			" give either an %q column or both %q and %q columns",
+			l.name, FieldAmount, FieldDebit, FieldCredit)
+	}
+
+	return nil
+}
+
+// Name returns the name of the layout
+func (l CSVLayout) Name() string {
+	return l.name
+}
+
+// UsesHeader returns true if any column is given by the header name
+func (l CSVLayout) UsesHeader() bool {
+	for _, col := range l.cols {
+		if _, err := strconv.Atoi(col); err != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolve converts the layout into a map from field to column index. The
+// header should be nil if there is no header line.
+func (l CSVLayout) resolve(header []string) (colMap, error) {
+	cm := colMap{}
+
+	for field, col := range l.cols {
+		if n, err := strconv.Atoi(col); err == nil {
+			if n < 1 {
+				return nil,
+					fmt.Errorf("CSV layout %q: field %q: bad column number: %d",
+						l.name, field, n)
+			}
+
+			cm[field] = n - 1
+
+			continue
+		}
+
+		if header == nil {
+			return nil,
+				fmt.Errorf("CSV layout %q: field %q: column %q is given by"+
+					" name but there is no header line",
+					l.name, field, col)
+		}
+
+		idx := slices.IndexFunc(header, func(h string) bool {
+			return strings.EqualFold(strings.TrimSpace(h), col)
+		})
+		if idx < 0 {
+			return nil,
+				fmt.Errorf("CSV layout %q: field %q:"+
+					" there is no column called %q in the header line",
+					l.name, field, col)
+		}
+
+		cm[field] = idx
+	}
+
+	return cm, nil
+}
+
+// get returns the value of the field from the parts. If the field is not in
+// the map an empty string is returned.
+func (cm colMap) get(parts []string, field string) (string, error) {
+	idx, ok := cm[field]
+	if !ok {
+		return "", nil
+	}
+
+	if idx >= len(parts) {
+		return "",
+			fmt.Errorf("the %s column (%d) is missing, there are only %d",
+				field, idx+1, len(parts))
+	}
+
+	return strings.TrimSpace(parts[idx]), nil
+}
+
+// has returns true if the field is in the map
+func (cm colMap) has(field string) bool {
+	_, ok := cm[field]
+	return ok
+}
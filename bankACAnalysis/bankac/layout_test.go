@@ -0,0 +1,137 @@
+package bankac
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestParseCSVLayout(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		def       string
+		expLayout CSVLayout
+	}{
+		{
+			ID:  testhelper.MkID("amount column, default date format"),
+			def: "mybank:date=Date,desc=Description,amount=3",
+			expLayout: CSVLayout{
+				name:       "mybank",
+				dateFormat: DfltDateFormat,
+				cols: map[string]string{
+					FieldDate:   "Date",
+					FieldDesc:   "Description",
+					FieldAmount: "3",
+				},
+			},
+		},
+		{
+			ID: testhelper.MkID("date format with a comma"),
+			def: "us:date=1,desc=2,debit=3,credit=4," +
+				LayoutDateFormat + "=Jan 2, 2006",
+			expLayout: CSVLayout{
+				name:       "us",
+				dateFormat: "Jan 2, 2006",
+				cols: map[string]string{
+					FieldDate:   "1",
+					FieldDesc:   "2",
+					FieldDebit:  "3",
+					FieldCredit: "4",
+				},
+			},
+		},
+		{
+			ID:     testhelper.MkID("no name"),
+			ExpErr: testhelper.MkExpErr("there is no ':' after the name"),
+			def:    "date=1,desc=2,amount=3",
+		},
+		{
+			ID:     testhelper.MkID("empty name"),
+			ExpErr: testhelper.MkExpErr("the name is missing"),
+			def:    " :date=1,desc=2,amount=3",
+		},
+		{
+			ID:     testhelper.MkID("first part has no '='"),
+			ExpErr: testhelper.MkExpErr(`"date" has no '='`),
+			def:    "x:date,desc=2,amount=3",
+		},
+		{
+			ID:     testhelper.MkID("empty part"),
+			ExpErr: testhelper.MkExpErr(`"" has no '='`),
+			def:    "x:date=1,desc=2,amount=3,",
+		},
+		{
+			ID:     testhelper.MkID("no column"),
+			ExpErr: testhelper.MkExpErr(`no column for "desc"`),
+			def:    "x:date=1,desc=,amount=3",
+		},
+		{
+			ID:     testhelper.MkID("unknown field"),
+			ExpErr: testhelper.MkExpErr(`unknown field: "memo"`),
+			def:    "x:date=1,desc=2,amount=3,memo=4",
+		},
+		{
+			ID:     testhelper.MkID("field given twice"),
+			ExpErr: testhelper.MkExpErr(`field "desc" is given twice`),
+			def:    "x:date=1,desc=2,desc=3,amount=4",
+		},
+		{
+			ID:     testhelper.MkID("no date"),
+			ExpErr: testhelper.MkExpErr(`the "date" column must be given`),
+			def:    "x:desc=2,amount=3",
+		},
+		{
+			ID: testhelper.MkID("amount and debit"),
+			ExpErr: testhelper.MkExpErr(
+				`give either an "amount" column or "debit" and "credit"` +
+					` columns, not both`),
+			def: "x:date=1,desc=2,amount=3,debit=4",
+		},
+		{
+			ID: testhelper.MkID("no amounts"),
+			ExpErr: testhelper.MkExpErr(
+				`give either an "amount" column or both "debit" and` +
+					` "credit" columns`),
+			def: "x:date=1,desc=2,debit=3",
+		},
+	}
+
+	for _, tc := range testCases {
+		l, err := ParseCSVLayout(tc.def)
+		if testhelper.CheckExpErr(t, err, tc) && err == nil {
+			testhelper.DiffValsReport(t, tc.IDStr(), "layout",
+				l, tc.expLayout)
+		}
+	}
+}
+
+func TestDfltLayout(t *testing.T) {
+	l := DfltLayout()
+
+	if err := l.check(); err != nil {
+		t.Errorf("the default layout is not valid: %s", err)
+	}
+
+	testhelper.DiffString(t, "default layout", "name",
+		l.Name(), DfltLayoutName)
+	testhelper.DiffBool(t, "default layout", "uses header",
+		l.UsesHeader(), false)
+
+	cm, err := l.resolve(nil)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	testhelper.DiffValsReport(t, "default layout", "columns", cm,
+		colMap{
+			FieldDate:     0,
+			FieldType:     1,
+			FieldSortCode: 2,
+			FieldAccount:  3,
+			FieldDesc:     4,
+			FieldDebit:    5,
+			FieldCredit:   6,
+			FieldBalance:  7,
+		})
+}
@@ -0,0 +1,195 @@
+package bankac
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+)
+
+// ofxTypes maps the OFX transaction types onto the transaction type codes
+// used in the bank's comma-separated values files. Any types not in the map
+// are used unchanged.
+var ofxTypes = map[string]string{
+	"CHECK":       XaTypeCheque,
+	"ATM":         XaTypeCash,
+	"CASH":        XaTypeCash,
+	"DIRECTDEBIT": XaTypeDirectDebit,
+	"REPEATPMT":   XaTypeStandingOrder,
+}
+
+// ofxToken records a single OFX tag and the text which follows it
+type ofxToken struct {
+	lineNum int
+	tag     string
+	val     string
+}
+
+// ofxTokens splits the OFX text into a sequence of tags and their
+// values. This will work for both the SGML-style files (OFX version 1) where
+// the tags have no closing tags and for the XML-style files (OFX version
+// 2). The header lines before the first tag are ignored.
+func ofxTokens(text string) []ofxToken {
+	toks := []ofxToken{}
+	lineNum := 1
+
+	for {
+		start := strings.IndexByte(text, '<')
+		if start < 0 {
+			break
+		}
+
+		lineNum += strings.Count(text[:start], "\n")
+		text = text[start+1:]
+
+		end := strings.IndexByte(text, '>')
+		if end < 0 {
+			break
+		}
+
+		tok := ofxToken{
+			lineNum: lineNum,
+			tag:     strings.ToUpper(strings.TrimSpace(text[:end])),
+		}
+		lineNum += strings.Count(text[:end], "\n")
+		text = text[end+1:]
+
+		valEnd := strings.IndexByte(text, '<')
+		if valEnd < 0 {
+			valEnd = len(text)
+		}
+
+		tok.val = html.UnescapeString(strings.TrimSpace(text[:valEnd]))
+		toks = append(toks, tok)
+	}
+
+	return toks
+}
+
+// parseOFXDate parses the OFX date which is of the form
+// YYYYMMDD[HHMMSS[.XXX]][[offset:TZ]]. Only the date part is used.
+func parseOFXDate(s string) (time.Time, error) {
+	const dateLen = len("20060102")
+
+	if len(s) < dateLen {
+		return time.Time{}, fmt.Errorf("bad OFX date: %q", s)
+	}
+
+	return time.Parse("20060102", s[:dateLen])
+}
+
+// ofxAmount converts the OFX amount into a form that can be parsed. In OFX
+// the decimal separator may be a comma so a single comma followed by
+// exactly two digits is taken as the decimal point. Otherwise any commas
+// are taken as thousands separators and removed.
+func ofxAmount(s string) string {
+	const centDigits = 2
+
+	if !strings.Contains(s, ".") && strings.Count(s, ",") == 1 {
+		_, cents, _ := strings.Cut(s, ",")
+		if len(cents) == centDigits &&
+			strings.Trim(cents, "0123456789") == "" {
+			return strings.Replace(s, ",", ".", 1)
+		}
+	}
+
+	return strings.ReplaceAll(s, ",", "")
+}
+
+// mkOFXXactn converts the values from an OFX STMTTRN block into a
+// transaction
+func mkOFXXactn(vals map[string]string) (Xactn, error) {
+	date, err := parseOFXDate(vals["DTPOSTED"])
+	if err != nil {
+		return Xactn{}, fmt.Errorf("couldn't parse the date: %s", err)
+	}
+
+	amt, err := parseNum(ofxAmount(vals["TRNAMT"]), "amount")
+	if err != nil {
+		return Xactn{}, err
+	}
+
+	xaType := vals["TRNTYPE"]
+	if t, ok := ofxTypes[xaType]; ok {
+		xaType = t
+	}
+
+	desc := vals["NAME"]
+	if desc == "" {
+		desc = vals["MEMO"]
+	}
+
+	if desc == "" {
+		desc = vals["CHECKNUM"]
+	}
+
+	xa := Xactn{
+		Date: date,
+		Type: xaType,
+		Desc: desc,
+	}
+	xa.SetAmount(amt)
+
+	return xa, nil
+}
+
+// ReadOFX reads the transactions from the OFX (or QFX) statement in the
+// io.Reader. Each STMTTRN block gives a transaction and the line number of
+// the transaction is the line on which the block starts. The account is
+// taken from the BANKID and ACCTID values and the currency from the CURDEF
+// value. Blocks which cannot be converted into transactions are passed to
+// BadEntry and skipped.
+func (rd *Reader) ReadOFX(name string, r io.Reader) ([]Xactn, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	xas := []Xactn{}
+
+	var (
+		vals      map[string]string
+		startLine int
+		acct      = map[string]string{}
+	)
+
+	for _, tok := range ofxTokens(string(content)) {
+		switch tok.tag {
+		case "STMTTRN":
+			vals = map[string]string{}
+			startLine = tok.lineNum
+		case "/STMTTRN":
+			if vals == nil {
+				continue
+			}
+
+			xa, err := mkOFXXactn(vals)
+			if err != nil {
+				rd.badEntry(name, startLine, err)
+			} else {
+				xa.FileName = name
+				xa.LineNum = startLine
+				xa.Account = strings.TrimSpace(
+					acct["BANKID"] + " " + acct["ACCTID"])
+				xa.Currency = strings.ToUpper(acct["CURDEF"])
+				xas = append(xas, xa)
+			}
+
+			vals = nil
+		case "BANKID", "ACCTID", "CURDEF":
+			acct[tok.tag] = tok.val
+		default:
+			if vals != nil && !strings.HasPrefix(tok.tag, "/") {
+				vals[tok.tag] = tok.val
+			}
+		}
+	}
+
+	if vals != nil {
+		return xas, fmt.Errorf("%s:%d: the transaction is not terminated",
+			name, startLine)
+	}
+
+	return xas, nil
+}
@@ -0,0 +1,129 @@
+package bankac
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// qifTypes maps the QIF reference (the 'N' line) onto the transaction type
+// codes used in the bank's comma-separated values files
+var qifTypes = map[string]string{
+	"ATM": XaTypeCash,
+	"DD":  XaTypeDirectDebit,
+	"SO":  XaTypeStandingOrder,
+}
+
+// qifDate parses the QIF date. Some programs write the year after an
+// apostrophe rather than a slash so this is converted before parsing.
+func (rd *Reader) qifDate(s string) (time.Time, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), "'", "/")
+
+	return time.Parse(rd.QIFDateFormat, s)
+}
+
+// mkQIFXactn converts the values from a QIF record into a transaction
+func (rd *Reader) mkQIFXactn(vals map[byte]string) (Xactn, error) {
+	date, err := rd.qifDate(vals['D'])
+	if err != nil {
+		return Xactn{}, fmt.Errorf("couldn't parse the date: %s", err)
+	}
+
+	amtStr, ok := vals['T']
+	if !ok {
+		amtStr = vals['U']
+	}
+
+	amt, err := parseNum(strings.ReplaceAll(amtStr, ",", ""), "amount")
+	if err != nil {
+		return Xactn{}, err
+	}
+
+	ref := strings.ToUpper(vals['N'])
+	xaType := ref
+
+	if t, ok := qifTypes[ref]; ok {
+		xaType = t
+	} else if _, err := strconv.Atoi(ref); err == nil {
+		xaType = XaTypeCheque
+	}
+
+	desc := vals['P']
+	if desc == "" {
+		desc = vals['M']
+	}
+
+	xa := Xactn{
+		Date: date,
+		Type: xaType,
+		Desc: desc,
+	}
+	xa.SetAmount(amt)
+
+	return xa, nil
+}
+
+// ReadQIF reads the transactions from the QIF statement in the
+// io.Reader. Each record is terminated by a line starting with '^' and the
+// line number of the transaction is the line on which the record
+// starts. Records which cannot be converted into transactions are passed
+// to BadEntry and skipped, as are any split ('S', 'E' and '$') lines.
+func (rd *Reader) ReadQIF(name string, r io.Reader) ([]Xactn, error) {
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	startLine := 0
+	vals := map[byte]string{}
+	xas := []Xactn{}
+
+	for scanner.Scan() {
+		lineNum++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '!' {
+			continue
+		}
+
+		if line[0] == '^' {
+			if len(vals) == 0 {
+				continue
+			}
+
+			xa, err := rd.mkQIFXactn(vals)
+			if err != nil {
+				rd.badEntry(name, startLine, err)
+			} else {
+				xa.FileName = name
+				xa.LineNum = startLine
+				xas = append(xas, xa)
+			}
+
+			vals = map[byte]string{}
+
+			continue
+		}
+
+		if len(vals) == 0 {
+			startLine = lineNum
+		}
+
+		switch code := line[0]; code {
+		case 'S', 'E', '$':
+		default:
+			vals[code] = strings.TrimSpace(line[1:])
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(vals) != 0 {
+		return xas, fmt.Errorf("%s:%d: the transaction is not terminated",
+			name, startLine)
+	}
+
+	return xas, nil
+}
@@ -0,0 +1,216 @@
+package bankac
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+// mkDate returns the date for the given year, month and day
+func mkDate(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestReadOFX(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		text   string
+		expXas []Xactn
+	}{
+		{
+			ID: testhelper.MkID("SGML, no closing tags"),
+			text: "OFXHEADER:100\n" +
+				"<OFX>\n" +
+				"<STMTTRN>\n" +
+				"<TRNTYPE>ATM\n" +
+				"<DTPOSTED>20240208120000[0:GMT]\n" +
+				"<TRNAMT>-50.00\n" +
+				"<NAME>LINK ATM\n" +
+				"</STMTTRN>\n" +
+				"</OFX>\n",
+			expXas: []Xactn{
+				{
+					FileName: "test",
+					LineNum:  3,
+					Date:     mkDate(2024, time.February, 8),
+					Type:     XaTypeCash,
+					Desc:     "LINK ATM",
+					DebitAmt: 50,
+				},
+			},
+		},
+		{
+			ID: testhelper.MkID("XML, memo but no name"),
+			text: "<OFX><STMTTRN><TRNTYPE>CREDIT</TRNTYPE>" +
+				"<DTPOSTED>20240209</DTPOSTED>" +
+				"<TRNAMT>1,500.00</TRNAMT>" +
+				"<MEMO>SALARY &amp; BONUS</MEMO></STMTTRN></OFX>",
+			expXas: []Xactn{
+				{
+					FileName:  "test",
+					LineNum:   1,
+					Date:      mkDate(2024, time.February, 9),
+					Type:      "CREDIT",
+					Desc:      "SALARY & BONUS",
+					CreditAmt: 1500,
+				},
+			},
+		},
+		{
+			ID: testhelper.MkID("comma as the decimal separator"),
+			text: "<OFX><STMTTRN><TRNTYPE>DEBIT</TRNTYPE>" +
+				"<DTPOSTED>20240210</DTPOSTED>" +
+				"<TRNAMT>-12,50</TRNAMT>" +
+				"<NAME>BOULANGERIE</NAME></STMTTRN></OFX>",
+			expXas: []Xactn{
+				{
+					FileName: "test",
+					LineNum:  1,
+					Date:     mkDate(2024, time.February, 10),
+					Type:     "DEBIT",
+					Desc:     "BOULANGERIE",
+					DebitAmt: 12.5,
+				},
+			},
+		},
+		{
+			ID: testhelper.MkID("comma as the thousands separator"),
+			text: "<OFX><STMTTRN><TRNTYPE>DEBIT</TRNTYPE>" +
+				"<DTPOSTED>20240211</DTPOSTED>" +
+				"<TRNAMT>-1,234</TRNAMT>" +
+				"<NAME>GARAGE</NAME></STMTTRN></OFX>",
+			expXas: []Xactn{
+				{
+					FileName: "test",
+					LineNum:  1,
+					Date:     mkDate(2024, time.February, 11),
+					Type:     "DEBIT",
+					Desc:     "GARAGE",
+					DebitAmt: 1234,
+				},
+			},
+		},
+		{
+			ID:     testhelper.MkID("unterminated"),
+			ExpErr: testhelper.MkExpErr("the transaction is not terminated"),
+			text:   "<OFX><STMTTRN><TRNTYPE>CREDIT",
+			expXas: []Xactn{},
+		},
+	}
+
+	for _, tc := range testCases {
+		xas, err := NewReader().ReadOFX("test", strings.NewReader(tc.text))
+		if testhelper.CheckExpErr(t, err, tc) && err == nil {
+			testhelper.DiffValsReport(t, tc.IDStr(), "transactions",
+				xas, tc.expXas)
+		}
+	}
+}
+
+func TestReadQIF(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		text   string
+		expXas []Xactn
+	}{
+		{
+			ID: testhelper.MkID("cash and cheque"),
+			text: "!Type:Bank\n" +
+				"D11/02'2024\n" +
+				"U-20.00\n" +
+				"NATM\n" +
+				"PCASH MACHINE\n" +
+				"^\n" +
+				"D12/02/2024\n" +
+				"T1,100.00\n" +
+				"N101\n" +
+				"PJ SMITH\n" +
+				"^\n",
+			expXas: []Xactn{
+				{
+					FileName: "test",
+					LineNum:  2,
+					Date:     mkDate(2024, time.February, 11),
+					Type:     XaTypeCash,
+					Desc:     "CASH MACHINE",
+					DebitAmt: 20,
+				},
+				{
+					FileName:  "test",
+					LineNum:   7,
+					Date:      mkDate(2024, time.February, 12),
+					Type:      XaTypeCheque,
+					Desc:      "J SMITH",
+					CreditAmt: 1100,
+				},
+			},
+		},
+		{
+			ID:     testhelper.MkID("unterminated"),
+			ExpErr: testhelper.MkExpErr("the transaction is not terminated"),
+			text:   "!Type:Bank\nD11/02/2024\n",
+			expXas: []Xactn{},
+		},
+	}
+
+	for _, tc := range testCases {
+		xas, err := NewReader().ReadQIF("test", strings.NewReader(tc.text))
+		if testhelper.CheckExpErr(t, err, tc) && err == nil {
+			testhelper.DiffValsReport(t, tc.IDStr(), "transactions",
+				xas, tc.expXas)
+		}
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	layout, err := ParseCSVLayout(
+		"test:date=Date,desc=Description,amount=Amount," +
+			LayoutDateFormat + "=2006-01-02")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	rd := NewReader()
+	rd.Layout = layout
+
+	badEntries := []string{}
+	rd.BadEntry = func(err error) {
+		badEntries = append(badEntries, err.Error())
+	}
+
+	xas, err := rd.ReadCSV("test", strings.NewReader(
+		"Amount,Date,Description\n"+
+			"-12.50,2024-02-08,TESCO\n"+
+			"x,2024-02-09,BAD AMOUNT\n"+
+			"1500,2024-02-10,SALARY\n"))
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	testhelper.DiffValsReport(t, "ReadCSV", "transactions", xas,
+		[]Xactn{
+			{
+				FileName: "test",
+				LineNum:  2,
+				Date:     mkDate(2024, time.February, 8),
+				Desc:     "TESCO",
+				DebitAmt: 12.5,
+			},
+			{
+				FileName:  "test",
+				LineNum:   4,
+				Date:      mkDate(2024, time.February, 10),
+				Desc:      "SALARY",
+				CreditAmt: 1500,
+			},
+		})
+	testhelper.DiffValsReport(t, "ReadCSV", "bad entries", badEntries,
+		[]string{
+			`test:3: couldn't parse the amount:` +
+				` strconv.ParseFloat: parsing "x": invalid syntax`,
+		})
+}
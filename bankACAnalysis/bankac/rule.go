@@ -0,0 +1,320 @@
+package bankac
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The keys that can be given in the categorisation rules file
+const (
+	RuleKeyDesc     = "desc"
+	RuleKeyType     = "type"
+	RuleKeyAmount   = "amount"
+	RuleKeyDay      = "day"
+	RuleKeyCategory = "category"
+	RuleKeyTag      = "tag"
+)
+
+const ruleErrIntro = "Bad categorisation rule"
+
+// Rule represents a categorisation rule. A transaction matches the rule if
+// it matches every test that has been given. The matching transactions are
+// placed in the rule's category or, for a tag rule, given the rule's tags.
+type Rule struct {
+	fileName string
+	lineNum  int
+
+	descRE *regexp.Regexp
+	types  map[string]bool
+
+	hasMinAmt bool
+	minAmt    float64
+	hasMaxAmt bool
+	maxAmt    float64
+
+	minDay int
+	maxDay int
+
+	category string
+	tags     []string
+}
+
+// String returns the location of the rule
+func (r Rule) String() string {
+	return fmt.Sprintf("%s:%d", r.fileName, r.lineNum)
+}
+
+// isEmpty returns true if the rule has no tests
+func (r Rule) isEmpty() bool {
+	return r.descRE == nil &&
+		r.types == nil &&
+		!r.hasMinAmt && !r.hasMaxAmt &&
+		r.minDay == 0 && r.maxDay == 0
+}
+
+// Matches returns true if the transaction passes all the tests of the rule
+func (r Rule) Matches(xa Xactn) bool {
+	if r.descRE != nil && !r.descRE.MatchString(xa.Desc) {
+		return false
+	}
+
+	if r.types != nil && !r.types[xa.Type] {
+		return false
+	}
+
+	amt := xa.DebitAmt + xa.CreditAmt
+	if r.hasMinAmt && amt < r.minAmt {
+		return false
+	}
+
+	if r.hasMaxAmt && amt > r.maxAmt {
+		return false
+	}
+
+	day := xa.Date.Day()
+	if r.minDay != 0 && day < r.minDay {
+		return false
+	}
+
+	if r.maxDay != 0 && day > r.maxDay {
+		return false
+	}
+
+	return true
+}
+
+// parseRange splits the value into the lower and upper parts of a range
+// which are separated by a colon. If there is no colon the value is used for
+// both parts. Either part may be empty.
+func parseRange(val string) (string, string) {
+	lo, hi, ok := strings.Cut(val, ":")
+	if !ok {
+		return val, val
+	}
+
+	return strings.TrimSpace(lo), strings.TrimSpace(hi)
+}
+
+// setAmount sets the amount range of the rule from the value
+func (r *Rule) setAmount(val string) error {
+	lo, hi := parseRange(val)
+
+	if lo != "" {
+		n, err := strconv.ParseFloat(lo, 64)
+		if err != nil {
+			return fmt.Errorf("bad minimum amount: %s", err)
+		}
+
+		r.hasMinAmt, r.minAmt = true, n
+	}
+
+	if hi != "" {
+		n, err := strconv.ParseFloat(hi, 64)
+		if err != nil {
+			return fmt.Errorf("bad maximum amount: %s", err)
+		}
+
+		r.hasMaxAmt, r.maxAmt = true, n
+	}
+
+	if r.hasMinAmt && r.hasMaxAmt && r.minAmt > r.maxAmt {
+		return fmt.Errorf("the minimum amount (%g) is greater than"+
+			" the maximum (%g)", r.minAmt, r.maxAmt)
+	}
+
+	return nil
+}
+
+// setDay sets the day-of-month range of the rule from the value
+func (r *Rule) setDay(val string) error {
+	const maxDayOfMonth = 31
+
+	lo, hi := parseRange(val)
+
+	for _, d := range []struct {
+		s    string
+		name string
+		v    *int
+	}{
+		{lo, "first", &r.minDay},
+		{hi, "last", &r.maxDay},
+	} {
+		if d.s == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(d.s)
+		if err != nil {
+			return fmt.Errorf("bad %s day: %s", d.name, err)
+		}
+
+		if n < 1 || n > maxDayOfMonth {
+			return fmt.Errorf("bad %s day: %d is not a day of the month",
+				d.name, n)
+		}
+
+		*d.v = n
+	}
+
+	if r.minDay != 0 && r.maxDay != 0 && r.minDay > r.maxDay {
+		return fmt.Errorf("the first day (%d) is after the last (%d)",
+			r.minDay, r.maxDay)
+	}
+
+	return nil
+}
+
+// ReadRules reads the categorisation rules from the io.Reader. Each rule is
+// given by one or more lines giving the tests to apply followed by a line
+// giving the category or the tags. Blank lines and lines starting with '#'
+// are ignored. Rules with errors are skipped and an error is returned for
+// each problem found.
+func (c *Categoriser) ReadRules(name string, rd io.Reader) []error {
+	rScanner := bufio.NewScanner(rd)
+	lineNum := 0
+	errs := []error{}
+
+	var (
+		r        Rule
+		errFound bool
+	)
+
+	badRule := func(lineNum int, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s:%d: %s: %s",
+			name, lineNum, ruleErrIntro, fmt.Sprintf(format, args...)))
+	}
+
+	for rScanner.Scan() {
+		lineNum++
+
+		line := rScanner.Text()
+
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		if r.lineNum == 0 {
+			r = Rule{fileName: name, lineNum: lineNum}
+		}
+
+		key, val, ok := strings.Cut(line, "=")
+		if !ok {
+			badRule(lineNum, "missing '=': %s", line)
+
+			errFound = true
+
+			continue
+		}
+
+		var (
+			err      error
+			ruleEnds bool
+		)
+
+		switch key {
+		case RuleKeyDesc:
+			r.descRE, err = regexp.Compile(val)
+		case RuleKeyType:
+			r.types = map[string]bool{}
+			for t := range strings.SplitSeq(val, ",") {
+				r.types[strings.TrimSpace(t)] = true
+			}
+		case RuleKeyAmount:
+			err = r.setAmount(val)
+		case RuleKeyDay:
+			err = r.setDay(val)
+		case RuleKeyCategory:
+			r.category = val
+			ruleEnds = true
+		case RuleKeyTag:
+			r.tags = parseTags(val)
+			if len(r.tags) == 0 {
+				err = errors.New("no tags are given")
+			}
+
+			ruleEnds = true
+		default:
+			err = fmt.Errorf("bad key: %q", key)
+		}
+
+		if err != nil {
+			badRule(lineNum, "%s", err)
+
+			errFound = true
+		}
+
+		if ruleEnds {
+			if err := c.addRule(r, errFound); err != nil {
+				badRule(lineNum, "%s", err)
+			}
+
+			r, errFound = Rule{}, false
+		}
+	}
+
+	if r.lineNum != 0 {
+		badRule(r.lineNum, "the rule has no %q or %q line",
+			RuleKeyCategory, RuleKeyTag)
+	}
+
+	if err := rScanner.Err(); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %w", name, err))
+	}
+
+	return errs
+}
+
+// addRule checks the rule and adds it to the rules if it is valid. A rule
+// with errors, which have already been reported, is ignored.
+func (c *Categoriser) addRule(r Rule, errFound bool) error {
+	if errFound {
+		return nil
+	}
+
+	if r.isEmpty() {
+		return errors.New("there are no tests")
+	}
+
+	if r.tags != nil {
+		c.tagRules = append(c.tagRules, r)
+		return nil
+	}
+
+	if _, ok := c.Tree().Parent(r.category); !ok {
+		return fmt.Errorf("the category (%q) is not in the %s",
+			r.category, mapDesc)
+	}
+
+	c.rules = append(c.rules, r)
+
+	return nil
+}
+
+// matchRule returns the first rule that matches the transaction or nil if
+// no rule matches
+func (c *Categoriser) matchRule(xa Categorised) *Rule {
+	for i, r := range c.rules {
+		if r.Matches(xa.Xactn) {
+			return &c.rules[i]
+		}
+	}
+
+	return nil
+}
+
+// applyRule places the transaction in the category given by the rule. The
+// transaction is summarised under its description unless that is already
+// in a different category in which case the name of the rule's category is
+// added to it to keep it distinct.
+func (c *Categoriser) applyRule(r *Rule, xa *Categorised) {
+	if err := c.setCategory(r.category, xa); err != nil {
+		c.problem(*xa, "Can't apply the rule (%s) to the %s: %s",
+			r, mapDesc, err)
+	}
+}
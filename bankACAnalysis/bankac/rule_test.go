@@ -0,0 +1,144 @@
+package bankac
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestReadRules(t *testing.T) {
+	tree := NewTree()
+	if err := tree.AddParent(CatAll, "bills"); err != nil {
+		t.Fatal("couldn't add the category:", err)
+	}
+
+	c := NewCategoriser(NewSummaries(tree))
+
+	errs := c.ReadRules("test", strings.NewReader(
+		"# a comment\n"+
+			"desc=^GAS\n"+
+			"  # an indented comment\n"+
+			"type=DD, SO\n"+
+			"category=bills\n"+
+			"\n"+
+			"amount=10:20\n"+
+			"tag=\n"+
+			"desc=^TESCO\n"+
+			"tag=food, weekly\n"+
+			"day=20:10\n"+
+			"category=bills\n"+
+			"desc=^LIDL\n"+
+			"category=nowhere\n"+
+			"category=bills\n"+
+			"desc=^CAFE\n"))
+
+	type expRule struct {
+		loc      string
+		category string
+		tags     []string
+	}
+
+	rules := []expRule{}
+	for _, r := range append(c.rules, c.tagRules...) {
+		rules = append(rules, expRule{r.String(), r.category, r.tags})
+	}
+
+	testhelper.DiffValsReport(t, "read rules", "rules", rules,
+		[]expRule{
+			{loc: "test:2", category: "bills"},
+			{loc: "test:9", tags: []string{"food", "weekly"}},
+		})
+
+	errStrs := []string{}
+	for _, err := range errs {
+		errStrs = append(errStrs, err.Error())
+	}
+
+	testhelper.DiffValsReport(t, "read rules", "errors", errStrs,
+		[]string{
+			"test:8: " + ruleErrIntro + ": no tags are given",
+			"test:11: " + ruleErrIntro +
+				": the first day (20) is after the last (10)",
+			"test:14: " + ruleErrIntro +
+				`: the category ("nowhere") is not in the ` + mapDesc,
+			"test:15: " + ruleErrIntro + ": there are no tests",
+			"test:16: " + ruleErrIntro +
+				`: the rule has no "category" or "tag" line`,
+		})
+}
+
+func TestRuleMatches(t *testing.T) {
+	c := NewCategoriser(NewSummaries(NewTree()))
+
+	errs := c.ReadRules("test", strings.NewReader(
+		"desc=^GAS\n"+
+			"type=DD,SO\n"+
+			"amount=10:20\n"+
+			"day=1:7\n"+
+			"category="+CatUnknown+"\n"+
+			"amount=:5\n"+
+			"category="+CatCash+"\n"))
+	if len(errs) != 0 {
+		t.Fatal("unexpected errors:", errs)
+	}
+
+	mkXactn := func(desc, xaType string, amt float64, day int) Categorised {
+		xa := Categorised{Xactn: Xactn{
+			Date: mkDate(2024, time.March, day),
+			Type: xaType,
+			Desc: desc,
+		}}
+		xa.SetAmount(amt)
+
+		return xa
+	}
+
+	testCases := []struct {
+		testhelper.ID
+		xa     Categorised
+		expCat string
+	}{
+		{
+			ID:     testhelper.MkID("all tests pass"),
+			xa:     mkXactn("GAS CO", "DD", -15, 3),
+			expCat: CatUnknown,
+		},
+		{
+			ID:     testhelper.MkID("amount bounds are included"),
+			xa:     mkXactn("GAS CO", "SO", 20, 7),
+			expCat: CatUnknown,
+		},
+		{
+			ID: testhelper.MkID("wrong description"),
+			xa: mkXactn("ELECTRIC CO", "DD", -15, 3),
+		},
+		{
+			ID: testhelper.MkID("wrong type"),
+			xa: mkXactn("GAS CO", "CHQ", -15, 3),
+		},
+		{
+			ID: testhelper.MkID("amount too big"),
+			xa: mkXactn("GAS CO", "DD", -20.01, 3),
+		},
+		{
+			ID: testhelper.MkID("day too late"),
+			xa: mkXactn("GAS CO", "DD", -15, 8),
+		},
+		{
+			ID:     testhelper.MkID("first rule fails, second matches"),
+			xa:     mkXactn("GAS CO", "DD", -5, 3),
+			expCat: CatCash,
+		},
+	}
+
+	for _, tc := range testCases {
+		cat := ""
+		if r := c.matchRule(tc.xa); r != nil {
+			cat = r.category
+		}
+
+		testhelper.DiffString(t, tc.IDStr(), "category", cat, tc.expCat)
+	}
+}
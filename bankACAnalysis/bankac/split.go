@@ -0,0 +1,319 @@
+package bankac
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const splitErrIntro = "Bad split entry"
+
+// splitPart gives a category and the share of a split transaction to be
+// put in it
+type splitPart struct {
+	category string
+	share    float64
+}
+
+// split records how a transaction is to be spread over several
+// categories. The shares of the parts are either percentages or, for a
+// split override, amounts; total is the sum of the shares.
+type split struct {
+	fileName string
+	lineNum  int
+	parts    []splitPart
+	total    float64
+}
+
+// String returns the location of the split
+func (sp split) String() string {
+	return fmt.Sprintf("%s:%d", sp.fileName, sp.lineNum)
+}
+
+// dateAmtKey identifies individual transactions by their date and amount
+type dateAmtKey struct {
+	date   string
+	amount float64
+}
+
+// dateAmtKey returns the date and amount which identify the transaction
+func (xa Xactn) dateAmtKey() dateAmtKey {
+	return dateAmtKey{
+		date:   xa.Date.Format(keyDateFormat),
+		amount: roundAmt(xa.DebitAmt + xa.CreditAmt),
+	}
+}
+
+// parseDateAmtKey parses the date and amount, separated by a space, which
+// identify individual transactions
+func parseDateAmtKey(key string) (dateAmtKey, error) {
+	const partCount = 2
+
+	parts := strings.Fields(key)
+	if len(parts) != partCount {
+		return dateAmtKey{},
+			fmt.Errorf("there should be %d parts (date, amount)"+
+				" before the '=', found %d", partCount, len(parts))
+	}
+
+	if _, err := time.Parse(keyDateFormat, parts[0]); err != nil {
+		return dateAmtKey{}, fmt.Errorf("bad date: %s", err)
+	}
+
+	amt, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return dateAmtKey{}, fmt.Errorf("bad amount: %s", err)
+	}
+
+	return dateAmtKey{date: parts[0], amount: roundAmt(amt)}, nil
+}
+
+// parseSplitParts parses a comma-separated list of category:share pairs.
+// There must be at least two parts and each share must be greater than
+// zero.
+func parseSplitParts(val string) ([]splitPart, error) {
+	const minParts = 2
+
+	parts := []splitPart{}
+
+	for p := range strings.SplitSeq(val, ",") {
+		i := strings.LastIndex(p, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("missing ':' in the part: %q", p)
+		}
+
+		cat := strings.TrimSpace(p[:i])
+		if cat == "" {
+			return nil, fmt.Errorf("missing category in the part: %q", p)
+		}
+
+		share, err := strconv.ParseFloat(
+			strings.TrimSuffix(strings.TrimSpace(p[i+1:]), "%"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad share for %q: %s", cat, err)
+		}
+
+		if share <= 0 {
+			return nil, fmt.Errorf("the share for %q (%g) must be"+
+				" greater than zero", cat, share)
+		}
+
+		parts = append(parts, splitPart{category: cat, share: share})
+	}
+
+	if len(parts) < minParts {
+		return nil, fmt.Errorf("there must be at least %d parts, found %d",
+			minParts, len(parts))
+	}
+
+	return parts, nil
+}
+
+// ReadSplits reads the splits of transactions by description from the
+// io.Reader. Each line gives the description then an '=' and then the
+// parts of the split as category:percentage pairs. Blank lines and lines
+// starting with '#' are ignored. Bad entries are skipped and an error is
+// returned for each one.
+func (c *Categoriser) ReadSplits(name string, r io.Reader) []error {
+	return c.readSplits(name, r, false)
+}
+
+// ReadSplitOverrides reads the splits of individual transactions from the
+// io.Reader. Each line gives the date and the amount of the transaction
+// then an '=' and then the parts of the split as category:amount pairs.
+// Blank lines and lines starting with '#' are ignored. Bad entries are
+// skipped and an error is returned for each one.
+func (c *Categoriser) ReadSplitOverrides(name string, r io.Reader) []error {
+	return c.readSplits(name, r, true)
+}
+
+// readSplits reads the splits, or the split overrides, from the reader
+func (c *Categoriser) readSplits(
+	name string, r io.Reader, override bool,
+) []error {
+	sScanner := bufio.NewScanner(r)
+	lineNum := 0
+	errs := []error{}
+
+	for sScanner.Scan() {
+		lineNum++
+
+		line := strings.TrimSpace(sScanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if err := c.addSplit(name, lineNum, line, override); err != nil {
+			errs = append(errs, fmt.Errorf("%s:%d: %s: %w",
+				name, lineNum, splitErrIntro, err))
+		}
+	}
+
+	if err := sScanner.Err(); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %w", name, err))
+	}
+
+	return errs
+}
+
+// addSplit parses the line from the split file and adds the split
+func (c *Categoriser) addSplit(
+	fileName string, lineNum int, line string, override bool,
+) error {
+	i := strings.LastIndex(line, "=")
+	if i < 0 {
+		return fmt.Errorf("missing '=': %s", line)
+	}
+
+	key := strings.TrimSpace(line[:i])
+
+	parts, err := parseSplitParts(line[i+1:])
+	if err != nil {
+		return err
+	}
+
+	sp := &split{fileName: fileName, lineNum: lineNum, parts: parts}
+
+	for _, p := range parts {
+		if _, ok := c.Tree().Parent(p.category); !ok {
+			return fmt.Errorf("the category (%q) is not in the %s",
+				p.category, mapDesc)
+		}
+
+		sp.total += p.share
+	}
+
+	if !override {
+		const pctTotal = 100
+
+		if math.Abs(sp.total-pctTotal) > amountTolerance {
+			return fmt.Errorf("the percentages add up to %g, not %d",
+				sp.total, pctTotal)
+		}
+
+		if prev, ok := c.splits[key]; ok {
+			return fmt.Errorf("%q is already split at %s", key, prev)
+		}
+
+		c.splits[key] = sp
+
+		return nil
+	}
+
+	sk, err := parseDateAmtKey(key)
+	if err != nil {
+		return err
+	}
+
+	if math.Abs(sp.total-sk.amount) > amountTolerance {
+		return fmt.Errorf("the amounts add up to %.2f, not %.2f",
+			sp.total, sk.amount)
+	}
+
+	if prev, ok := c.splitOverrides[sk]; ok {
+		return fmt.Errorf("the transaction is already split at %s", prev)
+	}
+
+	c.splitOverrides[sk] = sp
+
+	return nil
+}
+
+// findSplit returns the split to apply to the transaction or nil if it is
+// not to be split. A split override takes precedence over a split of the
+// description.
+func (c *Categoriser) findSplit(xa Categorised) *split {
+	if sp, ok := c.splitOverrides[xa.dateAmtKey()]; ok {
+		return sp
+	}
+
+	return c.splits[xa.Desc]
+}
+
+// apply returns the parts of the transaction, one for each part of the
+// split. The amounts are rounded to the nearest penny and the last part
+// takes whatever remains so that the parts add up to the transaction.
+func (sp split) apply(xa Categorised) []Categorised {
+	parts := make([]Categorised, 0, len(sp.parts))
+
+	var rest Xactn
+
+	rest.DebitAmt, rest.CreditAmt = xa.DebitAmt, xa.CreditAmt
+	rest.OrigDebitAmt, rest.OrigCreditAmt = xa.OrigDebitAmt, xa.OrigCreditAmt
+
+	for i, p := range sp.parts {
+		part := xa
+
+		if i == len(sp.parts)-1 {
+			part.DebitAmt, part.CreditAmt = rest.DebitAmt, rest.CreditAmt
+			part.OrigDebitAmt = rest.OrigDebitAmt
+			part.OrigCreditAmt = rest.OrigCreditAmt
+		} else {
+			frac := p.share / sp.total
+			part.DebitAmt = roundAmt(xa.DebitAmt * frac)
+			part.CreditAmt = roundAmt(xa.CreditAmt * frac)
+			part.OrigDebitAmt = roundAmt(xa.OrigDebitAmt * frac)
+			part.OrigCreditAmt = roundAmt(xa.OrigCreditAmt * frac)
+		}
+
+		rest.DebitAmt -= part.DebitAmt
+		rest.CreditAmt -= part.CreditAmt
+		rest.OrigDebitAmt -= part.OrigDebitAmt
+		rest.OrigCreditAmt -= part.OrigCreditAmt
+
+		parts = append(parts, part)
+	}
+
+	return parts
+}
+
+// MergeParts returns the transactions with the parts of each split
+// transaction put back together. The first part of each is kept, holding
+// the amounts of all its parts.
+func MergeParts(xas []Categorised) []Categorised {
+	merged := []Categorised{}
+	partOf := map[string]int{}
+
+	for _, xa := range xas {
+		if i, ok := partOf[xa.Location()]; ok {
+			merged[i].DebitAmt += xa.DebitAmt
+			merged[i].CreditAmt += xa.CreditAmt
+			merged[i].OrigDebitAmt += xa.OrigDebitAmt
+			merged[i].OrigCreditAmt += xa.OrigCreditAmt
+
+			continue
+		}
+
+		partOf[xa.Location()] = len(merged)
+		merged = append(merged, xa)
+	}
+
+	return merged
+}
+
+// addSplitXactn spreads the transaction over the categories of the split.
+// Each part is summarised in its own category but the transaction is only
+// counted once in any Summary which holds more than one of its parts. It
+// returns the parts.
+func (c *Categoriser) addSplitXactn(sp *split, xa Categorised) []Categorised {
+	c.note(xa, "is split by the entry at %s", sp)
+
+	counted := map[*Summary]bool{}
+	parts := sp.apply(xa)
+
+	for i := range parts {
+		err := c.setCategory(sp.parts[i].category, &parts[i])
+		if err != nil {
+			c.problem(xa, "Can't apply the split (%s) to the %s: %s",
+				sp, mapDesc, err)
+		}
+
+		c.summarise(parts[i], counted)
+	}
+
+	return parts
+}
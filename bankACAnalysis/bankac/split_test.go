@@ -0,0 +1,111 @@
+package bankac
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestReadSplits(t *testing.T) {
+	tree := NewTree()
+	for _, cat := range []string{"food", "household"} {
+		if err := tree.AddParent(CatAll, cat); err != nil {
+			t.Fatal("couldn't add the category:", err)
+		}
+	}
+
+	c := NewCategoriser(NewSummaries(tree))
+
+	errs := c.ReadSplits("splits", strings.NewReader(
+		"# comment\n"+
+			"TESCO = food:60%, household:40%\n"+
+			"LIDL = food:60, household:30\n"+
+			"ALDI = food:100\n"+
+			"COSTCO = food:50, garden:50\n"+
+			"TESCO = food:50, household:50\n"))
+	errs = append(errs, c.ReadSplitOverrides("overrides", strings.NewReader(
+		"2024-02-01 12.50 = food:10, household:2.50\n"+
+			"2024-02-02 10 = food:5, household:4\n"+
+			"2024-02 10 = food:5, household:5\n"))...)
+
+	testhelper.DiffValsReport(t, "read splits", "splits",
+		mapKeys(c.splits), []string{"TESCO"})
+	testhelper.DiffValsReport(t, "read splits", "overrides",
+		mapKeys(c.splitOverrides),
+		[]dateAmtKey{{date: "2024-02-01", amount: 12.5}})
+
+	errStrs := []string{}
+	for _, err := range errs {
+		errStrs = append(errStrs, err.Error())
+	}
+
+	testhelper.DiffValsReport(t, "read splits", "errors", errStrs,
+		[]string{
+			"splits:3: " + splitErrIntro +
+				": the percentages add up to 90, not 100",
+			"splits:4: " + splitErrIntro +
+				": there must be at least 2 parts, found 1",
+			"splits:5: " + splitErrIntro +
+				`: the category ("garden") is not in the ` + mapDesc,
+			"splits:6: " + splitErrIntro +
+				`: "TESCO" is already split at splits:2`,
+			"overrides:2: " + splitErrIntro +
+				": the amounts add up to 9.00, not 10.00",
+			"overrides:3: " + splitErrIntro +
+				`: bad date: parsing time "2024-02" as "2006-01-02":` +
+				` cannot parse "" as "-"`,
+		})
+}
+
+// mapKeys returns the keys of the map
+func mapKeys[K comparable, V any](m map[K]V) []K {
+	keys := []K{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	return keys
+}
+
+func TestSplitApply(t *testing.T) {
+	sp := split{
+		parts: []splitPart{
+			{category: "food", share: 1},
+			{category: "household", share: 1},
+			{category: "cash", share: 1},
+		},
+		total: 3,
+	}
+	xa := Categorised{
+		Xactn: Xactn{
+			Date:         mkDate(2024, time.February, 1),
+			DebitAmt:     10,
+			OrigDebitAmt: 11,
+		},
+	}
+
+	parts := sp.apply(xa)
+	testhelper.DiffInt(t, "split", "parts", len(parts), len(sp.parts))
+
+	expDebits := []float64{3.33, 3.33, 3.34}
+	total, origTotal := 0.0, 0.0
+
+	for i, p := range parts {
+		testhelper.DiffFloat(t, "split", "part debit",
+			p.DebitAmt, expDebits[i], 1e-9)
+
+		total += p.DebitAmt
+		origTotal += p.OrigDebitAmt
+	}
+
+	testhelper.DiffFloat(t, "split", "total", total, xa.DebitAmt, 1e-9)
+	testhelper.DiffFloat(t, "split", "original total",
+		origTotal, xa.OrigDebitAmt, 1e-9)
+
+	merged := MergeParts(parts)
+	testhelper.DiffInt(t, "merge", "transactions", len(merged), 1)
+	testhelper.DiffFloat(t, "merge", "debit",
+		merged[0].DebitAmt, xa.DebitAmt, 1e-9)
+}
@@ -0,0 +1,277 @@
+package bankac
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Amounts holds the totals for a Summary over some period
+type Amounts struct {
+	Count     int
+	DebitAmt  float64
+	CreditAmt float64
+}
+
+// Summary represents a summary of the account transactions for an entry in
+// the tree of categories. The totals include those of all the components
+// of the entry.
+type Summary struct {
+	Name       string
+	Count      int
+	FirstDate  time.Time
+	LastDate   time.Time
+	DebitAmt   float64
+	CreditAmt  float64
+	Parent     *Summary
+	Depth      int
+	Components map[string]*Summary
+
+	// ByMonth holds the totals for each month, keyed by the start of the
+	// month, and ByCurrency holds the totals in the original currency of
+	// the transactions, keyed by the currency
+	ByMonth    map[time.Time]*Amounts
+	ByCurrency map[string]*Amounts
+}
+
+// newSummary returns a Summary with the given name and parent, the parent
+// is nil for the top of the tree
+func newSummary(name string, parent *Summary) *Summary {
+	s := &Summary{
+		Name:       name,
+		Parent:     parent,
+		Components: make(map[string]*Summary),
+		ByMonth:    make(map[time.Time]*Amounts),
+		ByCurrency: make(map[string]*Amounts),
+	}
+
+	if parent != nil {
+		s.Depth = parent.Depth + 1
+		parent.Components[name] = s
+	}
+
+	return s
+}
+
+// monthStart returns the start of the month in which the time falls
+func monthStart(t time.Time) time.Time {
+	y, m, _ := t.Date()
+
+	return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// add will add the values to the summary record and move on to the parent
+// (if there is one). The transaction is not counted again if it has
+// already been counted in the summary record.
+func (s *Summary) add(xa Xactn, counted map[*Summary]bool) {
+	isNew := !counted[s]
+	if counted != nil {
+		counted[s] = true
+	}
+
+	if s.Count == 0 {
+		s.FirstDate = xa.Date
+		s.LastDate = xa.Date
+	} else {
+		if xa.Date.After(s.LastDate) {
+			s.LastDate = xa.Date
+		} else if s.FirstDate.After(xa.Date) {
+			s.FirstDate = xa.Date
+		}
+	}
+
+	if isNew {
+		s.Count++
+	}
+
+	s.DebitAmt += xa.DebitAmt
+	s.CreditAmt += xa.CreditAmt
+
+	month := monthStart(xa.Date)
+
+	ma, ok := s.ByMonth[month]
+	if !ok {
+		ma = &Amounts{}
+		s.ByMonth[month] = ma
+	}
+
+	if isNew {
+		ma.Count++
+	}
+
+	ma.DebitAmt += xa.DebitAmt
+	ma.CreditAmt += xa.CreditAmt
+
+	ca, ok := s.ByCurrency[xa.Currency]
+	if !ok {
+		ca = &Amounts{}
+		s.ByCurrency[xa.Currency] = ca
+	}
+
+	if isNew {
+		ca.Count++
+	}
+
+	ca.DebitAmt += xa.OrigDebitAmt
+	ca.CreditAmt += xa.OrigCreditAmt
+
+	if s.Parent != nil {
+		s.Parent.add(xa, counted)
+	}
+}
+
+// SortedComponents returns the components of the Summary in descending
+// order of the total amount of their transactions
+func (s *Summary) SortedComponents() []*Summary {
+	compList := []*Summary{}
+	for _, c := range s.Components {
+		compList = append(compList, c)
+	}
+
+	sort.Slice(compList, func(i, j int) bool {
+		return (compList[i].DebitAmt + compList[i].CreditAmt) >
+			(compList[j].DebitAmt + compList[j].CreditAmt)
+	})
+
+	return compList
+}
+
+// Currencies returns the currencies of the Summary's transactions in
+// alphabetical order
+func (s *Summary) Currencies() []string {
+	ccys := []string{}
+	for ccy := range s.ByCurrency {
+		ccys = append(ccys, ccy)
+	}
+
+	sort.Strings(ccys)
+
+	return ccys
+}
+
+// ParentName returns the name of the parent of the Summary or the empty
+// string if it has no parent
+func (s *Summary) ParentName() string {
+	if s.Parent == nil {
+		return ""
+	}
+
+	return s.Parent.Name
+}
+
+// AmountsBetween returns the totals of the transactions for the Summary in
+// the months starting from the start of the month of the first time and up
+// to, but not including, the second
+func (s *Summary) AmountsBetween(from, to time.Time) Amounts {
+	var a Amounts
+
+	for m := monthStart(from); m.Before(to); m = m.AddDate(0, 1, 0) {
+		if ma, ok := s.ByMonth[m]; ok {
+			a.Count += ma.Count
+			a.DebitAmt += ma.DebitAmt
+			a.CreditAmt += ma.CreditAmt
+		}
+	}
+
+	return a
+}
+
+// Summaries holds the Summary record for each entry in the tree of
+// categories, the top of the tree is the Summary for CatAll
+type Summaries struct {
+	tree      *Tree
+	summaries map[string]*Summary
+
+	maxDepth     int
+	maxNameWidth int
+}
+
+// NewSummaries returns the Summaries for the tree with a Summary record for
+// each entry in the tree
+func NewSummaries(t *Tree) *Summaries {
+	s := &Summaries{
+		tree: t,
+		summaries: map[string]*Summary{
+			CatAll: newSummary(CatAll, nil),
+		},
+	}
+
+	s.addSummaries(CatAll)
+
+	return s
+}
+
+// Tree returns the tree of categories
+func (s *Summaries) Tree() *Tree {
+	return s.tree
+}
+
+// Summary returns the Summary record for the named entry. It returns nil
+// if the entry is not in the tree.
+func (s *Summaries) Summary(name string) *Summary {
+	return s.summaries[name]
+}
+
+// MaxDepth returns the depth of the deepest entry in the tree
+func (s *Summaries) MaxDepth() int {
+	return s.maxDepth
+}
+
+// MaxNameWidth returns the length of the longest name in the tree
+func (s *Summaries) MaxNameWidth() int {
+	return s.maxNameWidth
+}
+
+// AddParent adds the parent/child relationship to the tree of categories
+// and creates the Summary record for the child, if it is new. It is an
+// error if the parent does not already exist.
+func (s *Summaries) AddParent(parent, child string) error {
+	if err := s.tree.AddParent(parent, child); err != nil {
+		return err
+	}
+
+	s.addSummary(parent, child)
+
+	return nil
+}
+
+// addSummaries creates the Summary records for the components of the named
+// entry in the tree of categories, and for their components in turn
+func (s *Summaries) addSummaries(name string) {
+	for _, child := range s.tree.Children(name) {
+		s.addSummary(name, child)
+		s.addSummaries(child)
+	}
+}
+
+// addSummary creates the Summary record for the child, if it doesn't
+// already have one, and adds it to the components of its parent
+func (s *Summaries) addSummary(parent, child string) {
+	if _, ok := s.summaries[child]; ok {
+		return
+	}
+
+	cSum := newSummary(child, s.summaries[parent])
+	s.summaries[child] = cSum
+
+	s.maxDepth = max(cSum.Depth, s.maxDepth)
+	s.maxNameWidth = max(len(cSum.Name), s.maxNameWidth)
+}
+
+// Add will add the transaction to the Summary record of the named entry
+// and to those above it in the tree. The counted map records the Summary
+// records which have already counted the transaction, it should be nil
+// unless the transaction is part of a split transaction. It is an error if
+// the entry is not in the tree.
+func (s *Summaries) Add(
+	name string, xa Xactn, counted map[*Summary]bool,
+) error {
+	summ, ok := s.summaries[name]
+	if !ok {
+		return fmt.Errorf("there is no summary record for %q", name)
+	}
+
+	summ.add(xa, counted)
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package bankac
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestSummaries(t *testing.T) {
+	tree := NewTree()
+	if errs := tree.ReadFlatMap("test", strings.NewReader(
+		"all food\n"+
+			"food TESCO\n"+
+			"all household\n")); len(errs) != 0 {
+		t.Fatal("unexpected errors:", errs)
+	}
+
+	s := NewSummaries(tree)
+
+	if err := s.AddParent("household", "DIY"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	mkXactn := func(month time.Month, amt float64) Xactn {
+		return Xactn{
+			Date:         time.Date(2024, month, 10, 0, 0, 0, 0, time.UTC),
+			DebitAmt:     amt,
+			Currency:     "GBP",
+			OrigDebitAmt: amt,
+		}
+	}
+
+	// the last two are the parts of a split transaction
+	counted := map[*Summary]bool{}
+	for _, add := range []struct {
+		name    string
+		xa      Xactn
+		counted map[*Summary]bool
+	}{
+		{name: "TESCO", xa: mkXactn(time.January, 10)},
+		{name: "TESCO", xa: mkXactn(time.February, 20), counted: counted},
+		{name: "DIY", xa: mkXactn(time.February, 5), counted: counted},
+	} {
+		if err := s.Add(add.name, add.xa, add.counted); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	if err := s.Add("LIDL", mkXactn(time.March, 1), nil); err == nil {
+		t.Error("an entry not in the tree should give an error")
+	}
+
+	testCases := []struct {
+		testhelper.ID
+		name     string
+		expCount int
+		expDebit float64
+		expDepth int
+		expFeb   Amounts
+	}{
+		{
+			ID:       testhelper.MkID("top"),
+			name:     CatAll,
+			expCount: 2,
+			expDebit: 35,
+			expFeb:   Amounts{Count: 1, DebitAmt: 25},
+		},
+		{
+			ID:       testhelper.MkID("category"),
+			name:     "food",
+			expCount: 2,
+			expDebit: 30,
+			expDepth: 1,
+			expFeb:   Amounts{Count: 1, DebitAmt: 20},
+		},
+		{
+			ID:       testhelper.MkID("added entry"),
+			name:     "DIY",
+			expCount: 1,
+			expDebit: 5,
+			expDepth: 2,
+			expFeb:   Amounts{Count: 1, DebitAmt: 5},
+		},
+	}
+
+	feb := time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, tc := range testCases {
+		summ := s.Summary(tc.name)
+		if summ == nil {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: there is no summary for %q", tc.name)
+
+			continue
+		}
+
+		testhelper.DiffInt(t, tc.IDStr(), "count", summ.Count, tc.expCount)
+		testhelper.DiffFloat(t, tc.IDStr(), "debit",
+			summ.DebitAmt, tc.expDebit, 1e-9)
+		testhelper.DiffInt(t, tc.IDStr(), "depth", summ.Depth, tc.expDepth)
+		testhelper.DiffValsReport(t, tc.IDStr(), "February",
+			summ.AmountsBetween(feb, feb.AddDate(0, 1, 0)), tc.expFeb)
+		testhelper.DiffValsReport(t, tc.IDStr(), "GBP",
+			*summ.ByCurrency["GBP"],
+			Amounts{Count: tc.expCount, DebitAmt: tc.expDebit})
+	}
+
+	testhelper.DiffInt(t, "summaries", "max depth", s.MaxDepth(), 2)
+	testhelper.DiffInt(t, "summaries", "max name width",
+		s.MaxNameWidth(), len("household"))
+}
@@ -0,0 +1,97 @@
+package bankac
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+const tagErrIntro = "Bad tag entry"
+
+// parseTags splits the comma-separated list of tags, ignoring any empty
+// ones
+func parseTags(val string) []string {
+	tags := []string{}
+
+	for t := range strings.SplitSeq(val, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+
+	return tags
+}
+
+// addTags adds the tags to the transaction, ignoring any it already has
+func (xa *Categorised) addTags(tags []string) {
+	for _, t := range tags {
+		if !slices.Contains(xa.Tags, t) {
+			xa.Tags = append(xa.Tags, t)
+		}
+	}
+}
+
+// ReadTags reads the tags from the io.Reader. Each line gives a
+// description, or the date and amount of a transaction, then an '=' and
+// then the tags. Blank lines and lines starting with '#' are ignored. Bad
+// entries are skipped and an error is returned for each one.
+func (c *Categoriser) ReadTags(name string, r io.Reader) []error {
+	tScanner := bufio.NewScanner(r)
+	lineNum := 0
+	errs := []error{}
+
+	for tScanner.Scan() {
+		lineNum++
+
+		line := strings.TrimSpace(tScanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		i := strings.LastIndex(line, "=")
+		if i < 0 {
+			errs = append(errs, fmt.Errorf("%s:%d: %s: missing '=': %s",
+				name, lineNum, tagErrIntro, line))
+
+			continue
+		}
+
+		key := strings.TrimSpace(line[:i])
+
+		tags := parseTags(line[i+1:])
+		if len(tags) == 0 {
+			errs = append(errs, fmt.Errorf("%s:%d: %s: no tags are given",
+				name, lineNum, tagErrIntro))
+
+			continue
+		}
+
+		if k, err := parseDateAmtKey(key); err == nil {
+			c.xactnTags[k] = append(c.xactnTags[k], tags...)
+		} else {
+			c.descTags[key] = append(c.descTags[key], tags...)
+		}
+	}
+
+	if err := tScanner.Err(); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %w", name, err))
+	}
+
+	return errs
+}
+
+// tagXactn gives the transaction the tags for its description and for the
+// transaction itself from the tag file, together with the tags of every
+// tag rule which it matches
+func (c *Categoriser) tagXactn(xa *Categorised) {
+	xa.addTags(c.descTags[xa.Desc])
+	xa.addTags(c.xactnTags[xa.dateAmtKey()])
+
+	for _, r := range c.tagRules {
+		if r.Matches(xa.Xactn) {
+			xa.addTags(r.tags)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package bankac
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestReadTags(t *testing.T) {
+	c := NewCategoriser(NewSummaries(NewTree()))
+
+	errs := c.ReadTags("test", strings.NewReader(
+		"# comment\n"+
+			"TESCO = food, weekly\n"+
+			"2024-02-01 12.50=holiday,,kids\n"+
+			"a=b LTD=work\n"+
+			"no equals\n"+
+			"CAFE=  ,\n"+
+			"TESCO=food\n"))
+
+	testhelper.DiffValsReport(t, "read tags", "description tags",
+		c.descTags, map[string][]string{
+			"TESCO":   {"food", "weekly", "food"},
+			"a=b LTD": {"work"},
+		})
+	testhelper.DiffValsReport(t, "read tags", "transaction tags",
+		c.xactnTags, map[dateAmtKey][]string{
+			{date: "2024-02-01", amount: 12.5}: {"holiday", "kids"},
+		})
+
+	errStrs := []string{}
+	for _, err := range errs {
+		errStrs = append(errStrs, err.Error())
+	}
+
+	testhelper.DiffValsReport(t, "read tags", "errors", errStrs,
+		[]string{
+			"test:5: " + tagErrIntro + ": missing '=': no equals",
+			"test:6: " + tagErrIntro + ": no tags are given",
+		})
+}
@@ -0,0 +1,133 @@
+package bankac
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// OwnAccount records the name of one of our own accounts and the account
+// identifier (the sort-code and account number) as found in the bank
+// account files
+type OwnAccount struct {
+	Name string
+	ID   string
+	Num  string
+}
+
+// ParseOwnAccount parses an own-account value which should be a name and
+// the sort-code and account number separated by '='
+func ParseOwnAccount(val string) (OwnAccount, error) {
+	name, id, ok := strings.Cut(val, "=")
+	if !ok {
+		return OwnAccount{},
+			fmt.Errorf("bad own account %q: it should be name=account", val)
+	}
+
+	name = strings.TrimSpace(name)
+	parts := strings.Fields(id)
+
+	if name == "" || len(parts) == 0 {
+		return OwnAccount{},
+			fmt.Errorf("bad own account %q:"+
+				" both the name and the account must be given", val)
+	}
+
+	return OwnAccount{
+		Name: name,
+		ID:   strings.Join(parts, " "),
+		Num:  parts[len(parts)-1],
+	}, nil
+}
+
+// OwnAccounts maps the account identifier to each of our own accounts
+type OwnAccounts map[string]OwnAccount
+
+// LooksLikeTransfer returns true if the transaction appears to be a
+// transfer to or from another of our own accounts. That is, if it is in one
+// of our accounts and the description mentions the name or the number of
+// another.
+func (oas OwnAccounts) LooksLikeTransfer(xa Xactn) bool {
+	if _, ok := oas[xa.Account]; !ok {
+		return false
+	}
+
+	desc := strings.ToUpper(xa.Desc)
+
+	for id, oa := range oas {
+		if id == xa.Account {
+			continue
+		}
+
+		if strings.Contains(desc, strings.ToUpper(oa.Name)) ||
+			strings.Contains(desc, oa.Num) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isTransferPair returns true if the debit and the credit are for the same
+// amount, are in different accounts and are no more than the given number
+// of days apart
+func isTransferPair(debit, credit Xactn, maxDays int) bool {
+	if debit.Account == credit.Account {
+		return false
+	}
+
+	if math.Abs(debit.DebitAmt-credit.CreditAmt) > amountTolerance {
+		return false
+	}
+
+	days := math.Abs(debit.Date.Sub(credit.Date).Hours() / hoursPerDay)
+
+	return days <= float64(maxDays)
+}
+
+// MarkTransfers finds the debits in one of our own accounts which match a
+// credit in another of our accounts, no more than maxDays apart, and marks
+// both as transfers. Each credit is matched with at most one debit, the
+// closest in date, and the debits are matched in date order.
+func (oas OwnAccounts) MarkTransfers(xas []Categorised, maxDays int) {
+	var debits, credits []int
+
+	for i, xa := range xas {
+		if _, ok := oas[xa.Account]; !ok {
+			continue
+		}
+
+		switch {
+		case xa.DebitAmt != 0 && xa.CreditAmt == 0:
+			debits = append(debits, i)
+		case xa.CreditAmt != 0 && xa.DebitAmt == 0:
+			credits = append(credits, i)
+		}
+	}
+
+	slices.SortStableFunc(debits, func(a, b int) int {
+		return xas[a].Date.Compare(xas[b].Date)
+	})
+
+	for _, d := range debits {
+		best := -1
+
+		for _, c := range credits {
+			if xas[c].IsTransfer ||
+				!isTransferPair(xas[d].Xactn, xas[c].Xactn, maxDays) {
+				continue
+			}
+
+			if best < 0 ||
+				math.Abs(xas[d].Date.Sub(xas[c].Date).Hours()) <
+					math.Abs(xas[d].Date.Sub(xas[best].Date).Hours()) {
+				best = c
+			}
+		}
+
+		if best >= 0 {
+			xas[d].IsTransfer, xas[best].IsTransfer = true, true
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package bankac
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestParseOwnAccount(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		val   string
+		expOA OwnAccount
+	}{
+		{
+			ID:    testhelper.MkID("good"),
+			val:   " savings = 44-55-66   456 ",
+			expOA: OwnAccount{Name: "savings", ID: "44-55-66 456", Num: "456"},
+		},
+		{
+			ID:     testhelper.MkID("no '='"),
+			ExpErr: testhelper.MkExpErr("it should be name=account"),
+			val:    "savings 456",
+		},
+		{
+			ID: testhelper.MkID("no account"),
+			ExpErr: testhelper.MkExpErr(
+				"both the name and the account must be given"),
+			val: "savings=",
+		},
+	}
+
+	for _, tc := range testCases {
+		oa, err := ParseOwnAccount(tc.val)
+		if testhelper.CheckExpErr(t, err, tc) && err == nil {
+			testhelper.DiffValsReport(t, tc.IDStr(), "own account",
+				oa, tc.expOA)
+		}
+	}
+}
+
+func TestLooksLikeTransfer(t *testing.T) {
+	oas := OwnAccounts{}
+
+	for _, val := range []string{"current=11-22-33 123", "savings=456"} {
+		oa, err := ParseOwnAccount(val)
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		oas[oa.ID] = oa
+	}
+
+	testCases := []struct {
+		testhelper.ID
+		xa     Xactn
+		expRes bool
+	}{
+		{
+			ID:     testhelper.MkID("mentions the other account's name"),
+			xa:     Xactn{Account: "11-22-33 123", Desc: "To Savings"},
+			expRes: true,
+		},
+		{
+			ID:     testhelper.MkID("mentions the other account's number"),
+			xa:     Xactn{Account: "456", Desc: "FROM 123"},
+			expRes: true,
+		},
+		{
+			ID: testhelper.MkID("mentions its own account"),
+			xa: Xactn{Account: "456", Desc: "SAVINGS INTEREST"},
+		},
+		{
+			ID: testhelper.MkID("not one of our accounts"),
+			xa: Xactn{Account: "789", Desc: "TO SAVINGS"},
+		},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffBool(t, tc.IDStr(), "looks like a transfer",
+			oas.LooksLikeTransfer(tc.xa), tc.expRes)
+	}
+}
@@ -0,0 +1,443 @@
+package bankac
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// The standard categories, every tree has these
+const (
+	CatAll     = "all"
+	CatUnknown = "unknown"
+	CatCash    = "cash"
+	CatCheque  = "cheque"
+)
+
+// NestedDescPrefix starts the lines giving transaction descriptions in the
+// nested form of the map and NestedPatternPrefix starts the lines giving
+// patterns matching transaction descriptions
+const (
+	NestedDescPrefix    = "- "
+	NestedPatternPrefix = "~ "
+)
+
+const (
+	mapDesc = "map of transaction types"
+
+	tabWidth = 4
+)
+
+// Tree records the tree of categories into which the transactions are
+// arranged. The leaves of the tree are the transaction descriptions and
+// every entry other than CatAll, the top of the tree, has a parent.
+type Tree struct {
+	parentOf map[string]string
+	children map[string][]string
+
+	// mapLine records the line on which each entry was first given in the
+	// map file
+	mapLine map[string]int
+
+	// cats records the entries given as categories in a nested map, these
+	// are categories even if they have no children
+	cats map[string]bool
+
+	// patterns holds the patterns given in a nested map in the order in
+	// which they were given
+	patterns []pattern
+}
+
+// pattern records a regular expression given in the map file and the
+// category to which the descriptions it matches belong
+type pattern struct {
+	text     string
+	re       *regexp.Regexp
+	category string
+	lineNum  int
+}
+
+// NewTree returns a Tree holding just the standard categories
+func NewTree() *Tree {
+	t := &Tree{
+		parentOf: map[string]string{CatAll: CatAll},
+		children: map[string][]string{},
+		mapLine:  map[string]int{},
+		cats:     map[string]bool{},
+	}
+
+	for _, cat := range []string{CatUnknown, CatCash, CatCheque} {
+		_ = t.AddParent(CatAll, cat) // can't fail, CatAll is always present
+	}
+
+	return t
+}
+
+// AddParent adds the parent/child relationship so that a given entry can
+// find its parent. It is an error if the parent does not already exist or
+// if the child already has a different parent.
+func (t *Tree) AddParent(parent, child string) error {
+	if _, ok := t.parentOf[parent]; !ok {
+		return fmt.Errorf("%q (parent of %q) doesn't exist",
+			parent, child)
+	}
+
+	if oldParent, ok := t.parentOf[child]; ok {
+		if oldParent != parent {
+			return fmt.Errorf("%q already has a parent: %q != %q",
+				child, parent, oldParent)
+		}
+
+		return nil
+	}
+
+	t.parentOf[child] = parent
+	t.children[parent] = append(t.children[parent], child)
+
+	return nil
+}
+
+// Parent returns the parent of the named entry. It returns false if the
+// entry is not in the tree. The parent of CatAll is itself.
+func (t *Tree) Parent(name string) (string, bool) {
+	parent, ok := t.parentOf[name]
+	return parent, ok
+}
+
+// Children returns the children of the named entry in the order in which
+// they were added
+func (t *Tree) Children(name string) []string {
+	return slices.Clone(t.children[name])
+}
+
+// MapLine returns the line on which the entry was first given in the map
+// file. It returns zero if the entry was not given in the map file.
+func (t *Tree) MapLine(name string) int {
+	return t.mapLine[name]
+}
+
+// PatternCategory returns the category of the first pattern which matches
+// the description. It returns false if no pattern matches.
+func (t *Tree) PatternCategory(desc string) (string, bool) {
+	for _, p := range t.patterns {
+		if p.re.MatchString(desc) {
+			return p.category, true
+		}
+	}
+
+	return "", false
+}
+
+// Maps returns true if the description is in the tree or if it matches one
+// of the patterns
+func (t *Tree) Maps(desc string) bool {
+	if _, ok := t.parentOf[desc]; ok {
+		return true
+	}
+
+	_, ok := t.PatternCategory(desc)
+
+	return ok
+}
+
+// InCategory returns true if the named entry is the category or is one of
+// its components, at any depth
+func (t *Tree) InCategory(name, cat string) bool {
+	for {
+		parent, ok := t.parentOf[name]
+		if !ok {
+			return false
+		}
+
+		if name == cat {
+			return true
+		}
+
+		if name == parent {
+			return false
+		}
+
+		name = parent
+	}
+}
+
+// addMapEntry adds the parent/child entry from the map file and records
+// the line on which the child was first given
+func (t *Tree) addMapEntry(
+	fileName string, lineNum int, parent, child string,
+) error {
+	err := t.AddParent(parent, child)
+	if err != nil {
+		return fmt.Errorf("%s:%d: Bad entry in the %s: %w",
+			fileName, lineNum, mapDesc, err)
+	}
+
+	if _, ok := t.mapLine[child]; !ok {
+		t.mapLine[child] = lineNum
+	}
+
+	return nil
+}
+
+// addPattern adds the pattern from the map file to the patterns of the
+// category
+func (t *Tree) addPattern(
+	fileName string, lineNum int, category, text string,
+) error {
+	re, err := regexp.Compile(text)
+	if err != nil {
+		return fmt.Errorf("%s:%d: Bad entry in the %s: bad pattern: %w",
+			fileName, lineNum, mapDesc, err)
+	}
+
+	t.patterns = append(t.patterns, pattern{
+		text:     text,
+		re:       re,
+		category: category,
+		lineNum:  lineNum,
+	})
+
+	return nil
+}
+
+// ReadFlatMap reads the map from the io.Reader. Each line gives a parent
+// followed by a space and then the child. Bad entries are skipped and an
+// error is returned for each one.
+func (t *Tree) ReadFlatMap(fileName string, r io.Reader) []error {
+	mScanner := bufio.NewScanner(r)
+	lineNum := 0
+	errs := []error{}
+
+	for mScanner.Scan() {
+		lineNum++
+
+		line := mScanner.Text()
+		if line == "" {
+			continue
+		}
+
+		from, to, ok := strings.Cut(line, " ")
+		if !ok {
+			errs = append(errs,
+				fmt.Errorf("%s:%d: Bad entry in the %s:"+
+					" there is no 'to' part",
+					fileName, lineNum, mapDesc))
+
+			continue
+		}
+
+		if err := t.addMapEntry(fileName, lineNum, from, to); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := mScanner.Err(); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %w", fileName, err))
+	}
+
+	return errs
+}
+
+// nestedLevel records a category in the nested map and the indent of the
+// line on which it was given
+type nestedLevel struct {
+	indent int
+	name   string
+}
+
+// indentOf returns the width of the leading white space of the line; a tab
+// counts as tabWidth spaces
+func indentOf(line string) int {
+	indent := 0
+
+	for _, r := range line {
+		switch r {
+		case ' ':
+			indent++
+		case '\t':
+			indent += tabWidth
+		default:
+			return indent
+		}
+	}
+
+	return indent
+}
+
+// ReadNestedMap reads the map from the io.Reader. The entries are nested
+// by their indentation. A line ending with a ':' gives a category, a line
+// starting with '- ' gives a transaction description and a line starting
+// with '~ ' gives a regular expression. Each entry belongs to the nearest
+// preceding category which is indented less than it; entries which are not
+// indented belong to the top-level category. Blank lines and lines starting
+// with '#' are ignored. For instance:
+//
+//	food:
+//	    groceries:
+//	        - TESCO
+//	        - SAINSBURYS
+//	        ~ ^LIDL
+//	    - CAFE NERO
+//	income:
+//	    - SALARY
+//
+// A description which is not in the tree is given to the category of the
+// first pattern that it matches, see PatternCategory. Bad entries are
+// skipped and an error is returned for each one.
+func (t *Tree) ReadNestedMap(fileName string, r io.Reader) []error {
+	mScanner := bufio.NewScanner(r)
+	lineNum := 0
+	stack := []nestedLevel{}
+	errs := []error{}
+
+	for mScanner.Scan() {
+		lineNum++
+
+		line := mScanner.Text()
+
+		entry := strings.TrimSpace(line)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		indent := indentOf(line)
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+
+		parent := CatAll
+		if len(stack) > 0 {
+			parent = stack[len(stack)-1].name
+		}
+
+		if text, ok := strings.CutPrefix(entry, NestedPatternPrefix); ok {
+			err := t.addPattern(fileName, lineNum,
+				parent, strings.TrimSpace(text))
+			if err != nil {
+				errs = append(errs, err)
+			}
+
+			continue
+		}
+
+		if desc, ok := strings.CutPrefix(entry, NestedDescPrefix); ok {
+			err := t.addMapEntry(fileName, lineNum,
+				parent, strings.TrimSpace(desc))
+			if err != nil {
+				errs = append(errs, err)
+			}
+
+			continue
+		}
+
+		cat, ok := strings.CutSuffix(entry, ":")
+		if !ok || strings.TrimSpace(cat) == "" {
+			errs = append(errs,
+				fmt.Errorf("%s:%d: Bad entry in the %s:"+
+					" expected either a category ending with ':',"+
+					" a description starting with %q"+
+					" or a pattern starting with %q",
+					fileName, lineNum, mapDesc,
+					NestedDescPrefix, NestedPatternPrefix))
+
+			continue
+		}
+
+		cat = strings.TrimSpace(cat)
+
+		// a category may be given again to add further entries
+		if t.parentOf[cat] != parent {
+			err := t.addMapEntry(fileName, lineNum, parent, cat)
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		if t.parentOf[cat] == parent {
+			t.cats[cat] = true
+		}
+
+		stack = append(stack, nestedLevel{indent: indent, name: cat})
+	}
+
+	if err := mScanner.Err(); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %w", fileName, err))
+	}
+
+	return errs
+}
+
+// mapItem is an entry or a pattern to be written in the nested map
+type mapItem struct {
+	name      string
+	lineNum   int
+	isPattern bool
+}
+
+// WriteNestedMap writes the entries and patterns read from the map file in
+// the nested form, as read by ReadNestedMap. They are written in the order
+// in which they were first given in the map file. An entry is written as a
+// category if it has children or if it was given as a category.
+func (t *Tree) WriteNestedMap(w io.Writer) error {
+	var write func(name string, depth int) error
+
+	write = func(name string, depth int) error {
+		items := []mapItem{}
+
+		for _, c := range t.children[name] {
+			if line, ok := t.mapLine[c]; ok || len(t.children[c]) > 0 {
+				items = append(items, mapItem{name: c, lineNum: line})
+			}
+		}
+
+		for _, p := range t.patterns {
+			if p.category == name {
+				items = append(items, mapItem{
+					name:      p.text,
+					lineNum:   p.lineNum,
+					isPattern: true,
+				})
+			}
+		}
+
+		slices.SortStableFunc(items, func(a, b mapItem) int {
+			return a.lineNum - b.lineNum
+		})
+
+		indent := strings.Repeat(" ", tabWidth*depth)
+
+		for _, item := range items {
+			var line string
+
+			switch {
+			case item.isPattern:
+				line = indent + NestedPatternPrefix + item.name
+			case len(t.children[item.name]) == 0 && !t.cats[item.name]:
+				line = indent + NestedDescPrefix + item.name
+			}
+
+			if line != "" {
+				if _, err := fmt.Fprintln(w, line); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			if _, err := fmt.Fprintln(w, indent+item.name+":"); err != nil {
+				return err
+			}
+
+			if err := write(item.name, depth+1); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return write(CatAll, 0)
+}
@@ -0,0 +1,188 @@
+package bankac
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestNestedMap(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		text        string
+		expParentOf map[string]string
+		expErrs     int
+		expText     string
+	}{
+		{
+			ID: testhelper.MkID("nested, with a category given twice"),
+			text: "# comment\n" +
+				"food:\n" +
+				"    groceries:\n" +
+				"        - TESCO\n" +
+				"\n" +
+				"        - SAINSBURYS\n" +
+				"        ~ ^ALDI\n" +
+				"    - CAFE NERO\n" +
+				"- PUB\n" +
+				"food:\n" +
+				"\tgroceries:\n" +
+				"\t\t- LIDL\n" +
+				"travel:\n" +
+				"    ~ RAIL|TRAIN\n",
+			expParentOf: map[string]string{
+				CatAll:       CatAll,
+				"food":       CatAll,
+				"groceries":  "food",
+				"TESCO":      "groceries",
+				"SAINSBURYS": "groceries",
+				"CAFE NERO":  "food",
+				"PUB":        CatAll,
+				"LIDL":       "groceries",
+				"travel":     CatAll,
+			},
+			expText: "food:\n" +
+				"    groceries:\n" +
+				"        - TESCO\n" +
+				"        - SAINSBURYS\n" +
+				"        ~ ^ALDI\n" +
+				"        - LIDL\n" +
+				"    - CAFE NERO\n" +
+				"- PUB\n" +
+				"travel:\n" +
+				"    ~ RAIL|TRAIN\n",
+		},
+		{
+			ID: testhelper.MkID("bad entries"),
+			text: "food:\n" +
+				"    TESCO\n" +
+				"    - TESCO\n" +
+				"income:\n" +
+				"    - TESCO\n" +
+				"    ~ (SALARY\n",
+			expParentOf: map[string]string{
+				CatAll:   CatAll,
+				"food":   CatAll,
+				"TESCO":  "food",
+				"income": CatAll,
+			},
+			expErrs: 3,
+			expText: "food:\n" +
+				"    - TESCO\n" +
+				"income:\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		tree := NewTree()
+		errs := tree.ReadNestedMap("test", strings.NewReader(tc.text))
+
+		for name, expParent := range tc.expParentOf {
+			parent, _ := tree.Parent(name)
+			testhelper.DiffString(t, tc.IDStr(), "parent of "+name,
+				parent, expParent)
+		}
+
+		testhelper.DiffInt(t, tc.IDStr(), "errors", len(errs), tc.expErrs)
+
+		var b strings.Builder
+		if err := tree.WriteNestedMap(&b); err != nil {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: unexpected error: %s", err)
+
+			continue
+		}
+
+		testhelper.DiffString(t, tc.IDStr(), "nested map",
+			b.String(), tc.expText)
+	}
+}
+
+func TestPatternCategory(t *testing.T) {
+	tree := NewTree()
+
+	errs := tree.ReadNestedMap("test", strings.NewReader(
+		"food:\n"+
+			"    - TESCO\n"+
+			"    ~ ^TESCO\n"+
+			"    ~ CAFE\n"+
+			"travel:\n"+
+			"    ~ ^(RAIL|TRAIN)\n"+
+			"    ~ CAFE\n"))
+	testhelper.DiffInt(t, "read the map", "errors", len(errs), 0)
+
+	testCases := []struct {
+		testhelper.ID
+		desc    string
+		expCat  string
+		expOK   bool
+		expMaps bool
+	}{
+		{
+			ID:      testhelper.MkID("in the tree"),
+			desc:    "TESCO",
+			expCat:  "food",
+			expOK:   true,
+			expMaps: true,
+		},
+		{
+			ID:      testhelper.MkID("matches a pattern"),
+			desc:    "TRAINLINE",
+			expCat:  "travel",
+			expOK:   true,
+			expMaps: true,
+		},
+		{
+			ID:      testhelper.MkID("the first pattern to match is used"),
+			desc:    "STATION CAFE",
+			expCat:  "food",
+			expOK:   true,
+			expMaps: true,
+		},
+		{
+			ID:   testhelper.MkID("no match"),
+			desc: "CINEMA",
+		},
+	}
+
+	for _, tc := range testCases {
+		cat, ok := tree.PatternCategory(tc.desc)
+		testhelper.DiffString(t, tc.IDStr(), "category", cat, tc.expCat)
+		testhelper.DiffBool(t, tc.IDStr(), "matched", ok, tc.expOK)
+		testhelper.DiffBool(t, tc.IDStr(), "maps",
+			tree.Maps(tc.desc), tc.expMaps)
+	}
+}
+
+func TestInCategory(t *testing.T) {
+	tree := NewTree()
+
+	errs := tree.ReadFlatMap("test", strings.NewReader(
+		"all food\n"+
+			"food groceries\n"+
+			"groceries TESCO\n"+
+			"nowhere LIDL\n"+
+			"all TESCO\n"+
+			"groceries\n"))
+	testhelper.DiffInt(t, "read the map", "errors", len(errs), 3)
+
+	testCases := []struct {
+		testhelper.ID
+		name   string
+		cat    string
+		expVal bool
+	}{
+		{ID: testhelper.MkID("leaf"), name: "TESCO", cat: "food", expVal: true},
+		{ID: testhelper.MkID("self"), name: "food", cat: "food", expVal: true},
+		{ID: testhelper.MkID("top"), name: CatAll, cat: CatAll, expVal: true},
+		{ID: testhelper.MkID("other"), name: CatCash, cat: "food"},
+		{ID: testhelper.MkID("missing"), name: "LIDL", cat: CatAll},
+		{ID: testhelper.MkID("no 'to' part"), name: "", cat: CatAll},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffBool(t, tc.IDStr(), "in category",
+			tree.InCategory(tc.name, tc.cat), tc.expVal)
+	}
+}
@@ -0,0 +1,129 @@
+package bankac
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Xactn represents a single transaction as read from a bank account file
+type Xactn struct {
+	FileName  string
+	LineNum   int
+	Date      time.Time
+	Type      string
+	Desc      string
+	DebitAmt  float64
+	CreditAmt float64
+	Balance   float64
+
+	// HasBalance is set if the balance was given
+	HasBalance bool
+
+	// Currency is the currency of the account and Account identifies the
+	// bank account, they are empty if not known
+	Currency string
+	Account  string
+
+	// OrigDebitAmt and OrigCreditAmt are the amounts in the currency of
+	// the account, they are set when the debit and credit amounts are
+	// converted to another currency
+	OrigDebitAmt  float64
+	OrigCreditAmt float64
+}
+
+// The formats of the bank account files
+const (
+	FmtCSV = "csv"
+	FmtOFX = "ofx"
+	FmtQIF = "qif"
+)
+
+// The transaction type codes which are given special treatment
+const (
+	XaTypeCheque        = "CHQ"
+	XaTypeCash          = "CPT"
+	XaTypeDirectDebit   = "DD"
+	XaTypeStandingOrder = "SO"
+)
+
+// SetAmount sets the debit or credit amount of the transaction from the
+// signed amount; a negative amount is a debit
+func (xa *Xactn) SetAmount(amt float64) {
+	if amt < 0 {
+		xa.DebitAmt = -amt
+	} else {
+		xa.CreditAmt = amt
+	}
+}
+
+// Location returns the file name and line number of the transaction
+func (xa Xactn) Location() string {
+	return fmt.Sprintf("%s:%d", xa.FileName, xa.LineNum)
+}
+
+// FormatOf returns the format of the named file, chosen from the file
+// extension with any unrecognised extension being taken as CSV.
+func FormatOf(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".ofx", ".qfx":
+		return FmtOFX
+	case ".qif":
+		return FmtQIF
+	}
+
+	return FmtCSV
+}
+
+// Reader holds the settings used when reading the transactions from a bank
+// account file. The zero value is not ready to use, NewReader should be
+// called to create a Reader.
+type Reader struct {
+	// Layout gives the columns of a CSV file and SkipFirstLine is set if
+	// the first line of a CSV file holds the headings
+	Layout        CSVLayout
+	SkipFirstLine bool
+
+	// QIFDateFormat is the layout of the dates in a QIF file
+	QIFDateFormat string
+
+	// BadEntry, if it is not nil, is called with the error for each entry
+	// in the file which cannot be converted into a transaction. The entry
+	// is skipped.
+	BadEntry func(err error)
+}
+
+// NewReader returns a Reader with the default CSV layout, expecting a
+// header line in CSV files and with the default date format for QIF files
+func NewReader() *Reader {
+	return &Reader{
+		Layout:        DfltLayout(),
+		SkipFirstLine: true,
+		QIFDateFormat: DfltDateFormat,
+	}
+}
+
+// badEntry reports the error in the entry at the given line of the file
+func (rd *Reader) badEntry(name string, lineNum int, err error) {
+	if rd.BadEntry != nil {
+		rd.BadEntry(fmt.Errorf("%s:%d: %w", name, lineNum, err))
+	}
+}
+
+// Read reads the transactions from the io.Reader using the reader for the
+// given format. The name is recorded in each transaction as the file from
+// which it was read.
+func (rd *Reader) Read(format, name string, r io.Reader) ([]Xactn, error) {
+	switch format {
+	case FmtCSV:
+		return rd.ReadCSV(name, r)
+	case FmtOFX:
+		return rd.ReadOFX(name, r)
+	case FmtQIF:
+		return rd.ReadQIF(name, r)
+	default:
+		return nil, fmt.Errorf("unknown file format: %q", format)
+	}
+}
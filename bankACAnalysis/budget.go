@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nickwells/col.mod/v6/col"
+	"github.com/nickwells/col.mod/v6/colfmt"
+	"github.com/nickwells/personal-utils/bankACAnalysis/bankac"
+)
+
+// The recurring periods that a budget can be given for
+const (
+	budgetMonthly   = "monthly"
+	budgetQuarterly = "quarterly"
+	budgetYearly    = "yearly"
+)
+
+const budgetDesc = "budget"
+
+// budgetMonths gives the number of months in each recurring budget period
+var budgetMonths = map[string]int{
+	budgetMonthly:   1,
+	budgetQuarterly: 3,
+	budgetYearly:    12,
+}
+
+// budgetEntry records a single budget amount for a category. Either the
+// amount recurs every given number of months or else it applies to the
+// given date range.
+type budgetEntry struct {
+	amount float64
+	months int
+	dates  dateRange
+}
+
+// periodsIn returns the number of periods of the given number of months
+// between the two dates (inclusive). A partial period at the end is counted
+// as the proportion of its days that are included.
+func periodsIn(from, to time.Time, months int) float64 {
+	end := to.AddDate(0, 0, 1)
+	n := 0.0
+
+	for start := from; start.Before(end); {
+		next := start.AddDate(0, months, 0)
+		if next.After(end) {
+			n += end.Sub(start).Hours() / next.Sub(start).Hours()
+			break
+		}
+
+		n++
+		start = next
+	}
+
+	return n
+}
+
+// overlapFraction returns the proportion of the days in the budget date
+// range which are between the two dates (inclusive)
+func overlapFraction(dr dateRange, from, to time.Time) float64 {
+	start := dr.from
+	if from.After(start) {
+		start = from
+	}
+
+	end := dr.to
+	if to.Before(end) {
+		end = to
+	}
+
+	if end.Before(start) {
+		return 0
+	}
+
+	return (end.Sub(start).Hours()/hoursPerDay + 1) /
+		(dr.to.Sub(dr.from).Hours()/hoursPerDay + 1)
+}
+
+// amountFor returns the budget amount for the period between the two dates
+func (be budgetEntry) amountFor(from, to time.Time) float64 {
+	if be.months > 0 {
+		return be.amount * periodsIn(from, to, be.months)
+	}
+
+	return be.amount * overlapFraction(be.dates, from, to)
+}
+
+// parseBudgetEntry parses a line from the budget file. The line should have
+// the category, the period and the amount separated by spaces.
+func parseBudgetEntry(line string) (string, budgetEntry, error) {
+	const partCount = 3
+
+	parts := strings.Fields(line)
+	if len(parts) != partCount {
+		return "", budgetEntry{},
+			fmt.Errorf("there should be %d parts (category, period, amount),"+
+				" found %d", partCount, len(parts))
+	}
+
+	amt, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return "", budgetEntry{}, fmt.Errorf("bad amount: %s", err)
+	}
+
+	be := budgetEntry{amount: amt}
+
+	if months, ok := budgetMonths[parts[1]]; ok {
+		be.months = months
+	} else {
+		be.dates, err = parseNamedPeriod(parts[1])
+		if err != nil {
+			return "", budgetEntry{}, err
+		}
+	}
+
+	return parts[0], be, nil
+}
+
+// populateBudgets reads the budget file. Bad entries are reported and
+// ignored. It returns an error if the file cannot be opened.
+func (s *summaries) populateBudgets(prog *prog) error {
+	if prog.budgetFileName == "" {
+		return nil
+	}
+
+	bf, err := openFile(prog.budgetFileName, budgetDesc)
+	if err != nil {
+		return err
+	}
+	defer bf.Close()
+
+	s.budgets = map[string][]budgetEntry{}
+
+	bScanner := bufio.NewScanner(bf)
+	lineNum := 0
+
+	const errIntro = "Bad budget entry"
+
+	for bScanner.Scan() {
+		lineNum++
+
+		line := strings.TrimSpace(bScanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		cat, be, err := parseBudgetEntry(line)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s:%d: %s: %s\n",
+				prog.budgetFileName, lineNum, errIntro, err)
+
+			continue
+		}
+
+		if s.Summary(cat) == nil {
+			fmt.Fprintf(os.Stderr,
+				"%s:%d: %s: the category (%q) is not in the %s\n",
+				prog.budgetFileName, lineNum, errIntro, cat, xactnMapDesc)
+
+			continue
+		}
+
+		s.budgets[cat] = append(s.budgets[cat], be)
+	}
+
+	if err := bScanner.Err(); err != nil {
+		return fmt.Errorf("couldn't read the %s file: %w", budgetDesc, err)
+	}
+
+	return nil
+}
+
+// budgetDates returns the dates over which the budgets are calculated. This
+// is the date range being reported, with any unset bound taken from the
+// dates of the transactions.
+func (s *summaries) budgetDates(prog *prog) (time.Time, time.Time) {
+	from, to := prog.dates.from, prog.dates.to
+	all := s.Summary(bankac.CatAll)
+
+	if from.IsZero() {
+		from = all.FirstDate
+	}
+
+	if to.IsZero() {
+		to = all.LastDate
+	}
+
+	return from, to
+}
+
+// calcBudgets returns the budget for each Summary between the two dates. A
+// Summary with no budget of its own has the total of its components'
+// budgets.
+func (s *summaries) calcBudgets(
+	from, to time.Time,
+) map[*bankac.Summary]float64 {
+	budgets := map[*bankac.Summary]float64{}
+
+	var calc func(summ *bankac.Summary) (float64, bool)
+
+	calc = func(summ *bankac.Summary) (float64, bool) {
+		childTot, childHas := 0.0, false
+
+		for _, c := range summ.Components {
+			if b, ok := calc(c); ok {
+				childTot += b
+				childHas = true
+			}
+		}
+
+		entries, ok := s.budgets[summ.Name]
+		if !ok {
+			if childHas {
+				budgets[summ] = childTot
+			}
+
+			return childTot, childHas
+		}
+
+		tot := 0.0
+		for _, be := range entries {
+			tot += be.amountFor(from, to)
+		}
+
+		budgets[summ] = tot
+
+		return tot, true
+	}
+
+	calc(s.Summary(bankac.CatAll))
+
+	return budgets
+}
+
+// budgetReport will report the budget, actual spending, variance and
+// percentage of the budget used for each category with a budget
+func (s *summaries) budgetReport(prog *prog, cat string) {
+	const (
+		floatColWidth = 10
+		floatColPrec  = 2
+		pctColWidth   = 6
+	)
+
+	summ := s.Summary(cat)
+	if summ == nil {
+		fmt.Fprintf(os.Stderr, "*** category: %q is not recognised\n", cat)
+		return
+	}
+
+	from, to := s.budgetDates(prog)
+	budgets := s.calcBudgets(from, to)
+
+	floatCol := colfmt.Float{W: floatColWidth, Prec: floatColPrec}
+
+	rpt := col.NewReportOrPanic(col.NewHeaderOrPanic(), prog.out,
+		col.New(&colfmt.String{W: tabWidth*s.MaxDepth() + s.MaxNameWidth()},
+			"Transaction Type"),
+		col.New(&floatCol, "Budget"),
+		col.New(&floatCol, "Actual"),
+		col.New(&floatCol, "Variance"),
+		col.New(&colfmt.Percent{W: pctColWidth}, "%age", "Used"),
+		col.New(&colfmt.String{W: len(overBudgetFlag)}, ""),
+	)
+
+	prog.budgetRows(summ, rpt, budgets, 0)
+}
+
+const overBudgetFlag = "OVER"
+
+// budgetRows prints the budget row for the Summary and then for its
+// components. Only those entries having a budget are shown together with
+// the top-level entries, so that unbudgeted spending is visible.
+func (prog *prog) budgetRows(
+	s *bankac.Summary,
+	rpt *col.Report,
+	budgets map[*bankac.Summary]float64,
+	indent int,
+) {
+	budget, hasBudget := budgets[s]
+	if !hasBudget && (indent > 1 || prog.isHidden(s)) {
+		return
+	}
+
+	actual := s.DebitAmt - s.CreditAmt
+
+	// entries without a budget show only the actual spending
+	var budgetVal, varianceVal, pctVal any = col.Skip{}, col.Skip{}, col.Skip{}
+
+	flag := ""
+
+	if hasBudget {
+		budgetVal, varianceVal = budget, budget-actual
+		pctVal = calcPct(actual, budget)
+
+		if actual > budget+balanceTolerance {
+			flag = overBudgetFlag
+		}
+	}
+
+	err := rpt.PrintRow(
+		strings.Repeat(" ", tabWidth*indent)+s.Name,
+		budgetVal,
+		actual,
+		varianceVal,
+		pctVal,
+		flag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Couldn't print the row:", err)
+	}
+
+	for _, c := range s.SortedComponents() {
+		prog.budgetRows(c, rpt, budgets, indent+1)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nickwells/personal-utils/bankACAnalysis/bankac"
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestPeriodsIn(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		from, to time.Time
+		months   int
+		expVal   float64
+	}{
+		{
+			ID:     testhelper.MkID("whole year by month"),
+			from:   mkDate(2024, time.January, 1),
+			to:     mkDate(2024, time.December, 31),
+			months: 1,
+			expVal: 12,
+		},
+		{
+			ID:     testhelper.MkID("whole year by quarter"),
+			from:   mkDate(2024, time.January, 1),
+			to:     mkDate(2024, time.December, 31),
+			months: 3,
+			expVal: 4,
+		},
+		{
+			ID:     testhelper.MkID("part of a month"),
+			from:   mkDate(2024, time.January, 1),
+			to:     mkDate(2024, time.January, 15),
+			months: 1,
+			expVal: 15.0 / 31,
+		},
+		{
+			ID:     testhelper.MkID("a month and a half"),
+			from:   mkDate(2024, time.January, 16),
+			to:     mkDate(2024, time.March, 1),
+			months: 1,
+			expVal: 1 + 15.0/29,
+		},
+		{
+			ID:     testhelper.MkID("half a leap year"),
+			from:   mkDate(2024, time.January, 1),
+			to:     mkDate(2024, time.June, 30),
+			months: 12,
+			expVal: 182.0 / 366,
+		},
+		{
+			ID:     testhelper.MkID("a single day"),
+			from:   mkDate(2024, time.April, 1),
+			to:     mkDate(2024, time.April, 1),
+			months: 1,
+			expVal: 1.0 / 30,
+		},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffFloat(t, tc.IDStr(), "periods",
+			periodsIn(tc.from, tc.to, tc.months), tc.expVal, 1e-9)
+	}
+}
+
+func TestCalcBudgets(t *testing.T) {
+	tree := bankac.NewTree()
+	for _, entry := range [][2]string{
+		{bankac.CatAll, "food"},
+		{"food", "groceries"},
+		{"food", "cafes"},
+		{bankac.CatAll, "bills"},
+		{"bills", "gas"},
+		{bankac.CatAll, "travel"},
+	} {
+		if err := tree.AddParent(entry[0], entry[1]); err != nil {
+			t.Fatal("couldn't build the tree:", err)
+		}
+	}
+
+	feb, err := parseNamedPeriod("2024-02")
+	if err != nil {
+		t.Fatal("couldn't parse the period:", err)
+	}
+
+	s := &summaries{
+		Categoriser: bankac.NewCategoriser(bankac.NewSummaries(tree)),
+		budgets: map[string][]budgetEntry{
+			"groceries": {{amount: 100, months: 1}},
+			"cafes": {
+				{amount: 60, months: 3},
+				{amount: 29, dates: feb},
+			},
+			"bills": {{amount: 50, months: 1}},
+			"gas":   {{amount: 30, months: 1}},
+		},
+	}
+
+	budgets := s.calcBudgets(
+		mkDate(2024, time.January, 1), mkDate(2024, time.March, 31))
+
+	for _, exp := range []struct {
+		name      string
+		hasBudget bool
+		expBudget float64
+	}{
+		{name: "groceries", hasBudget: true, expBudget: 300},
+		{name: "cafes", hasBudget: true, expBudget: 89},
+		// a category with no budget of its own has its components' total
+		{name: "food", hasBudget: true, expBudget: 389},
+		{name: "gas", hasBudget: true, expBudget: 90},
+		// a category's own budget is used rather than its components'
+		{name: "bills", hasBudget: true, expBudget: 150},
+		{name: "travel"},
+		{name: bankac.CatAll, hasBudget: true, expBudget: 539},
+	} {
+		budget, ok := budgets[s.Summary(exp.name)]
+		testhelper.DiffBool(t, exp.name, "has a budget", ok, exp.hasBudget)
+		testhelper.DiffFloat(t, exp.name, "budget",
+			budget, exp.expBudget, 1e-9)
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/nickwells/personal-utils/bankACAnalysis/bankac"
+)
+
+const (
+	dfltChartDepth = 1
+	dfltChartTop   = 10
+	dfltChartWidth = 80
+
+	// chartAmtWidth is the width of the amounts shown beside the bars
+	chartAmtWidth = 10
+	// minBarWidth is the fewest characters that the longest bar will take
+	minBarWidth = 10
+)
+
+// barParts holds the block characters used to draw the end of a bar, each
+// a further eighth of a character wide. The first is used when the bar
+// ends on a whole character.
+var barParts = []rune(" ▏▎▍▌▋▊▉")
+
+// sparkChars holds the block characters used to draw a sparkline, in
+// order of increasing height
+var sparkChars = []rune("▁▂▃▄▅▆▇█")
+
+// bar returns a bar of block characters whose length is proportional to
+// the size of the value. The bar for the largest value is the given width.
+func bar(val, maxVal float64, width int) string {
+	const fullBlock = "█"
+
+	if maxVal <= 0 {
+		return ""
+	}
+
+	eighths := int(math.Round(
+		math.Abs(val) / maxVal * float64(width*len(barParts))))
+
+	b := strings.Repeat(fullBlock, eighths/len(barParts))
+	if part := eighths % len(barParts); part > 0 {
+		b += string(barParts[part])
+	}
+
+	return b
+}
+
+// sparkline returns a line of block characters, one per value, whose
+// heights are proportional to the size of the values
+func sparkline(vals []float64) string {
+	maxVal := 0.0
+	for _, v := range vals {
+		maxVal = max(maxVal, math.Abs(v))
+	}
+
+	var b strings.Builder
+
+	for _, v := range vals {
+		i := 0
+		if maxVal > 0 {
+			i = int(math.Round(
+				math.Abs(v) / maxVal * float64(len(sparkChars)-1)))
+		}
+
+		b.WriteRune(sparkChars[i])
+	}
+
+	return b.String()
+}
+
+// chartWidth returns the width in which the charts are drawn. This is the
+// width given by the user or else the width of the terminal, if it can be
+// found, or else a default width.
+func (prog *prog) chartWidth() int {
+	if prog.chartCols > 0 {
+		return prog.chartCols
+	}
+
+	if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 {
+		return w
+	}
+
+	if w, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && w > 0 {
+		return w
+	}
+
+	return dfltChartWidth
+}
+
+// chartBar holds the name and value of a bar in a chart
+type chartBar struct {
+	name string
+	val  float64
+}
+
+// drawBars prints a bar for each entry, after its name and value, with the
+// longest bar filling the rest of the chart width
+func (prog *prog) drawBars(bars []chartBar) {
+	nameWidth := 0
+	maxVal := 0.0
+
+	for _, b := range bars {
+		nameWidth = max(nameWidth, len(b.name))
+		maxVal = max(maxVal, math.Abs(b.val))
+	}
+
+	barWidth := max(minBarWidth,
+		prog.chartWidth()-nameWidth-chartAmtWidth-2)
+
+	for _, b := range bars {
+		line := fmt.Sprintf("%-*s %*.2f %s",
+			nameWidth, b.name,
+			chartAmtWidth, b.val,
+			bar(b.val, maxVal, barWidth))
+		fmt.Fprintln(prog.out, strings.TrimRight(line, " "))
+	}
+}
+
+// chartEntries returns the visible Summary records at the chart depth
+// below the Summary, together with any leaf entries above that depth so
+// that the whole of the Summary is shown
+func (prog *prog) chartEntries(s *bankac.Summary) []*bankac.Summary {
+	entries := []*bankac.Summary{}
+
+	_ = prog.walk(s, 0, func(summ *bankac.Summary, depth int) error {
+		if depth == prog.chartDepth ||
+			(depth > 0 && depth < prog.chartDepth &&
+				len(summ.Components) == 0) {
+			entries = append(entries, summ)
+		}
+
+		return nil
+	})
+
+	return entries
+}
+
+// chartReport will draw a bar chart of the largest entries at the chart
+// depth below the category followed by a bar for each month, and a
+// sparkline, of the value of the category
+func (s *summaries) chartReport(prog *prog, cat string) {
+	summ := s.Summary(cat)
+	if summ == nil {
+		fmt.Fprintf(os.Stderr, "*** category: %q is not recognised\n", cat)
+		return
+	}
+
+	vName := valueName(prog.periodValue)
+
+	bars := []chartBar{}
+	for _, e := range prog.chartEntries(summ) {
+		bars = append(bars, chartBar{
+			name: e.Name,
+			val: amountValue(bankac.Amounts{
+				DebitAmt:  e.DebitAmt,
+				CreditAmt: e.CreditAmt,
+			}, prog.periodValue),
+		})
+	}
+
+	slices.SortFunc(bars, func(a, b chartBar) int {
+		return cmp.Or(
+			cmp.Compare(math.Abs(b.val), math.Abs(a.val)),
+			cmp.Compare(a.name, b.name))
+	})
+
+	if len(bars) > prog.chartTop {
+		others := chartBar{
+			name: fmt.Sprintf("others (%d)", len(bars)-prog.chartTop),
+		}
+		for _, b := range bars[prog.chartTop:] {
+			others.val += b.val
+		}
+
+		bars = append(bars[:prog.chartTop], others)
+	}
+
+	fmt.Fprintf(prog.out, "%s: %s amounts at depth %d\n",
+		cat, vName, prog.chartDepth)
+	prog.drawBars(bars)
+
+	months := []chartBar{}
+	vals := []float64{}
+
+	for _, start := range s.periods(periodMonth) {
+		v := amountValue(periodAmounts(summ, start, periodMonth),
+			prog.periodValue)
+		months = append(months, chartBar{
+			name: periodName(start, periodMonth),
+			val:  v,
+		})
+		vals = append(vals, v)
+	}
+
+	fmt.Fprintln(prog.out)
+	fmt.Fprintf(prog.out, "%s: %s amounts by month\n", cat, vName)
+	prog.drawBars(months)
+	fmt.Fprintln(prog.out, sparkline(vals))
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestBar(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		val, maxVal float64
+		width       int
+		expBar      string
+	}{
+		{
+			ID:     testhelper.MkID("largest value"),
+			val:    10,
+			maxVal: 10,
+			width:  4,
+			expBar: "████",
+		},
+		{
+			ID:     testhelper.MkID("negative value, part character"),
+			val:    -5.5,
+			maxVal: 10,
+			width:  4,
+			expBar: "██▎",
+		},
+		{
+			ID:     testhelper.MkID("nothing to show"),
+			val:    0,
+			maxVal: 0,
+			width:  4,
+			expBar: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffString(t, tc.IDStr(), "bar",
+			bar(tc.val, tc.maxVal, tc.width), tc.expBar)
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	testhelper.DiffString(t, "sparkline", "line",
+		sparkline([]float64{0, -7, 14, 3.5}), "▁▅█▃")
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nickwells/personal-utils/bankACAnalysis/bankac"
+)
+
+// errQuit is returned when the user chooses to stop classifying
+var errQuit = errors.New("quit")
+
+// classifier holds the state needed while classifying the unknown
+// transactions
+type classifier struct {
+	s       *summaries
+	in      *bufio.Scanner
+	out     io.Writer
+	mapFile io.Writer
+	nested  bool
+	cats    []string
+}
+
+// categories returns the names of the categories in tree order. A category
+// is a child of the top-level group or any entry which has child
+// entries. The unknown group is not included as it cannot be chosen.
+func (s *summaries) categories() []string {
+	cats := []string{}
+
+	var walk func(summ *bankac.Summary)
+
+	walk = func(summ *bankac.Summary) {
+		for _, c := range summ.SortedComponents() {
+			if c.Name == bankac.CatUnknown {
+				continue
+			}
+
+			if c.Parent.Name == bankac.CatAll || len(c.Components) > 0 {
+				cats = append(cats, c.Name)
+				walk(c)
+			}
+		}
+	}
+
+	walk(s.Summary(bankac.CatAll))
+
+	return cats
+}
+
+// showCategories writes the numbered list of categories, indented to show
+// the tree structure
+func (c *classifier) showCategories() {
+	c.cats = c.s.categories()
+
+	fmt.Fprintln(c.out, "Categories:")
+
+	for i, cat := range c.cats {
+		fmt.Fprintf(c.out, "%4d: %s%s\n",
+			i+1,
+			strings.Repeat(" ", tabWidth*(c.s.Summary(cat).Depth-1)),
+			cat)
+	}
+}
+
+// prompt writes the prompt and returns the next line from the input. It
+// returns errQuit if the input is exhausted.
+func (c *classifier) prompt(msg string) (string, error) {
+	fmt.Fprint(c.out, msg)
+
+	if !c.in.Scan() {
+		if err := c.in.Err(); err != nil {
+			return "", err
+		}
+
+		return "", errQuit
+	}
+
+	return strings.TrimSpace(c.in.Text()), nil
+}
+
+// writeMapEntry appends the parent/child entry to the map file. If the map
+// file is nested the entry is written under the full path of the parent.
+func (c *classifier) writeMapEntry(parent, child string) error {
+	if !c.nested {
+		_, err := fmt.Fprintf(c.mapFile, "%s %s\n", parent, child)
+
+		return err
+	}
+
+	path := []string{}
+	top := c.s.Summary(bankac.CatAll)
+	for summ := c.s.Summary(parent); summ != nil && summ != top; {
+		path = append(path, summ.Name)
+		summ = summ.Parent
+	}
+
+	slices.Reverse(path)
+
+	var entry strings.Builder
+
+	for i, cat := range path {
+		entry.WriteString(strings.Repeat(" ", tabWidth*i) + cat + ":\n")
+	}
+
+	entry.WriteString(strings.Repeat(" ", tabWidth*len(path)) +
+		bankac.NestedDescPrefix + child + "\n")
+
+	_, err := io.WriteString(c.mapFile, entry.String())
+
+	return err
+}
+
+// newCategory asks for the parent of the new category, adds the category to
+// the tree and records it in the map file
+func (c *classifier) newCategory(cat string) (bool, error) {
+	if strings.ContainsAny(cat, " \t") {
+		fmt.Fprintf(c.out, "a category name cannot contain spaces: %q\n", cat)
+		return false, nil
+	}
+
+	for {
+		parent, err := c.prompt(fmt.Sprintf(
+			"%q is a new category, give its parent (default: %s): ",
+			cat, bankac.CatAll))
+		if err != nil {
+			return false, err
+		}
+
+		switch parent {
+		case "":
+			parent = bankac.CatAll
+		case "-":
+			return false, nil
+		}
+
+		if n, err := strconv.Atoi(parent); err == nil &&
+			n >= 1 && n <= len(c.cats) {
+			parent = c.cats[n-1]
+		}
+
+		if err := c.s.AddParent(parent, cat); err != nil {
+			fmt.Fprintln(c.out, err)
+			fmt.Fprintln(c.out, "enter '-' to cancel the new category")
+
+			continue
+		}
+
+		return true, c.writeMapEntry(parent, cat)
+	}
+}
+
+// chooseCategory asks the user for the category of the description. It
+// returns an empty string if the description is to be skipped.
+func (c *classifier) chooseCategory(desc string) (string, error) {
+	for {
+		ans, err := c.prompt("category (number or name," +
+			" blank to skip, ? to list, q to quit): ")
+		if err != nil {
+			return "", err
+		}
+
+		switch ans {
+		case "":
+			return "", nil
+		case "q":
+			return "", errQuit
+		case "?":
+			c.showCategories()
+			continue
+		}
+
+		if n, err := strconv.Atoi(ans); err == nil {
+			if n < 1 || n > len(c.cats) {
+				fmt.Fprintf(c.out, "choose a number between 1 and %d\n",
+					len(c.cats))
+
+				continue
+			}
+
+			return c.cats[n-1], nil
+		}
+
+		if ans == bankac.CatUnknown || ans == desc {
+			fmt.Fprintf(c.out, "%q cannot be chosen\n", ans)
+			continue
+		}
+
+		if _, ok := c.s.Tree().Parent(ans); ok {
+			return ans, nil
+		}
+
+		created, err := c.newCategory(ans)
+		if err != nil {
+			return "", err
+		}
+
+		if created {
+			return ans, nil
+		}
+	}
+}
+
+// classify walks through the unknown transaction descriptions in descending
+// order of their total amount and asks the user to choose a category for
+// each. The choices are appended to the map file.
+func (s *summaries) classify(
+	in io.Reader, out, mapFile io.Writer, nested bool,
+) error {
+	unknowns := s.Summary(bankac.CatUnknown).SortedComponents()
+	if len(unknowns) == 0 {
+		fmt.Fprintln(out, "There are no unknown transactions")
+		return nil
+	}
+
+	c := &classifier{
+		s:       s,
+		in:      bufio.NewScanner(in),
+		out:     out,
+		mapFile: mapFile,
+		nested:  nested,
+	}
+	c.showCategories()
+
+	for i, u := range unknowns {
+		fmt.Fprintf(out, "\n%d of %d: %q\n", i+1, len(unknowns), u.Name)
+		fmt.Fprintf(out, "    %d transactions, %s to %s,"+
+			" debits: %.2f, credits: %.2f\n",
+			u.Count,
+			u.FirstDate.Format(rptDateFormat),
+			u.LastDate.Format(rptDateFormat),
+			u.DebitAmt, u.CreditAmt)
+
+		cat, err := c.chooseCategory(u.Name)
+		if errors.Is(err, errQuit) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if cat == "" {
+			continue
+		}
+
+		if err := c.writeMapEntry(cat, u.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// classifyUnknowns runs the interactive classification, appending the
+// answers to the transaction map file
+func (prog *prog) classifyUnknowns(s *summaries) error {
+	content, err := os.ReadFile(prog.xactMapFileName)
+	if err != nil {
+		return err
+	}
+
+	mf, err := os.OpenFile(prog.xactMapFileName, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("couldn't open the %s for appending: %w",
+			xactnMapDesc, err)
+	}
+
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		_, err = fmt.Fprintln(mf)
+	}
+
+	if err == nil {
+		err = s.classify(os.Stdin, os.Stdout, mf,
+			prog.mapFormat == mapFmtNested)
+	}
+
+	if cErr := mf.Close(); cErr != nil && err == nil {
+		err = fmt.Errorf("couldn't close the %s: %w", xactnMapDesc, cErr)
+	}
+
+	return err
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/nickwells/col.mod/v6/col"
+	"github.com/nickwells/col.mod/v6/colfmt"
+	"github.com/nickwells/personal-utils/bankACAnalysis/bankac"
+)
+
+// comparing returns true if the report is to compare the transactions with
+// those in another period or in other files
+func (prog *prog) comparing() bool {
+	return prog.compareDates.isSet() || len(prog.compareFiles) > 0
+}
+
+// compareData returns the summaries to compare against. These are the
+// transactions in the comparison period taken from the comparison files,
+// if given, or else from the transactions already read.
+func (prog *prog) compareData(xas []Xactn) (*summaries, error) {
+	cp := *prog
+	cp.dates = prog.compareDates
+
+	if len(prog.compareFiles) > 0 {
+		cp.files = prog.compareFiles
+		cp.storeFileName = ""
+
+		err := cp.checkFiles()
+		if err == nil {
+			xas, err = cp.readAccountData()
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read the files to compare: %w",
+				err)
+		}
+	}
+
+	s, err := cp.initSummaries()
+	if err != nil {
+		return nil, err
+	}
+
+	s.addXactns(xas, cp.dates)
+
+	return s, nil
+}
+
+// comparedWith returns a description of what is being compared against
+func (prog *prog) comparedWith() string {
+	desc := prog.compareDates.String()
+
+	if len(prog.compareFiles) > 0 {
+		desc += " from: " + strings.Join(prog.compareFiles, ", ")
+	}
+
+	return desc
+}
+
+// summaryPair holds the Summary records with the same name from the two
+// sets of summaries being compared. Either may be nil if the name only
+// appears in one of them.
+type summaryPair struct {
+	name       string
+	this, prev *bankac.Summary
+}
+
+// totalAmt returns the total of the debits and credits in both summaries,
+// it is used to order the pairs
+func (sp summaryPair) totalAmt() float64 {
+	tot := 0.0
+
+	for _, summ := range []*bankac.Summary{sp.this, sp.prev} {
+		if summ != nil {
+			tot += summ.DebitAmt + summ.CreditAmt
+		}
+	}
+
+	return tot
+}
+
+// isHidden returns true if the Summary record would be hidden in both of
+// the summaries
+func (sp summaryPair) isHidden(prog *prog) bool {
+	return (sp.this == nil || prog.isHidden(sp.this)) &&
+		(sp.prev == nil || prog.isHidden(sp.prev))
+}
+
+// summaryValue returns the value to show for the Summary or zero if it is
+// nil
+func summaryValue(summ *bankac.Summary, v string) float64 {
+	if summ == nil {
+		return 0
+	}
+
+	a := bankac.Amounts{DebitAmt: summ.DebitAmt, CreditAmt: summ.CreditAmt}
+
+	return amountValue(a, v)
+}
+
+// components returns the pairs of the components of the two Summary
+// records, in descending order of their total amounts
+func (sp summaryPair) components() []summaryPair {
+	byName := map[string]*summaryPair{}
+	pairs := []*summaryPair{}
+
+	getPair := func(name string) *summaryPair {
+		p, ok := byName[name]
+		if !ok {
+			p = &summaryPair{name: name}
+			byName[name] = p
+			pairs = append(pairs, p)
+		}
+
+		return p
+	}
+
+	if sp.this != nil {
+		for name, c := range sp.this.Components {
+			getPair(name).this = c
+		}
+	}
+
+	if sp.prev != nil {
+		for name, c := range sp.prev.Components {
+			getPair(name).prev = c
+		}
+	}
+
+	comps := make([]summaryPair, 0, len(pairs))
+	for _, p := range pairs {
+		comps = append(comps, *p)
+	}
+
+	slices.SortFunc(comps, func(a, b summaryPair) int {
+		return cmp.Or(
+			cmp.Compare(b.totalAmt(), a.totalAmt()),
+			cmp.Compare(a.name, b.name))
+	})
+
+	return comps
+}
+
+// compareReport will report the value for each Summary in the category in
+// this period and in the period being compared against, together with the
+// change and the percentage change
+func (s *summaries) compareReport(prog *prog, cat string) {
+	const (
+		floatColWidth = 10
+		floatColPrec  = 2
+		pctColWidth   = 7
+	)
+
+	sp := summaryPair{
+		name: cat,
+		this: s.Summary(cat),
+		prev: s.compared.Summary(cat),
+	}
+	if sp.this == nil && sp.prev == nil {
+		fmt.Fprintf(os.Stderr, "*** category: %q is not recognised\n", cat)
+		return
+	}
+
+	maxDepth := max(s.MaxDepth(), s.compared.MaxDepth())
+	maxNameWidth := max(s.MaxNameWidth(), s.compared.MaxNameWidth())
+
+	floatCol := colfmt.Float{
+		W:    floatColWidth,
+		Prec: floatColPrec,
+		Zeroes: &colfmt.FloatZeroHandler{
+			Handle:  true,
+			Replace: "",
+		},
+	}
+	pctCol := colfmt.Percent{
+		W: pctColWidth,
+		Zeroes: &colfmt.FloatZeroHandler{
+			Handle:  true,
+			Replace: "",
+		},
+	}
+
+	vName := valueName(prog.periodValue)
+
+	rpt := col.NewReportOrPanic(col.NewHeaderOrPanic(), prog.out,
+		col.New(&colfmt.String{W: tabWidth*maxDepth + maxNameWidth},
+			"Transaction Type"),
+		col.New(&floatCol, vName, "This"),
+		col.New(&floatCol, vName, "Compared"),
+		col.New(&floatCol, "Change"),
+		col.New(&pctCol, "%age", "Change"),
+	)
+
+	sp.compareReport(prog, rpt, 0)
+}
+
+// compareReport prints the comparison row for the pair of Summary records
+// and then for their components
+func (sp summaryPair) compareReport(prog *prog, rpt *col.Report, indent int) {
+	if sp.isHidden(prog) {
+		return
+	}
+
+	this := summaryValue(sp.this, prog.periodValue)
+	prev := summaryValue(sp.prev, prog.periodValue)
+
+	err := rpt.PrintRow(
+		strings.Repeat(" ", tabWidth*indent)+sp.name,
+		this,
+		prev,
+		this-prev,
+		calcPct(this-prev, math.Abs(prev)))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Couldn't print the row:", err)
+	}
+
+	for _, c := range sp.components() {
+		c.compareReport(prog, rpt, indent+1)
+	}
+}
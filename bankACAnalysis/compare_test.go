@@ -0,0 +1,97 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nickwells/personal-utils/bankACAnalysis/bankac"
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+// mkSummaries returns the Summaries with a category for each of the debit
+// amounts, each holding a single transaction for that amount
+func mkSummaries(t *testing.T, debits map[string]float64) *bankac.Summaries {
+	t.Helper()
+
+	s := bankac.NewSummaries(bankac.NewTree())
+
+	for name, amt := range debits {
+		if err := s.AddParent(bankac.CatAll, name); err != nil {
+			t.Fatal("couldn't add the category:", err)
+		}
+
+		err := s.Add(name, bankac.Xactn{DebitAmt: amt}, nil)
+		if err != nil {
+			t.Fatal("couldn't add the transaction:", err)
+		}
+	}
+
+	return s
+}
+
+func TestSummaryPairComponents(t *testing.T) {
+	sp := summaryPair{
+		name: bankac.CatAll,
+		this: mkSummaries(t, map[string]float64{"food": 50, "travel": 30}).
+			Summary(bankac.CatAll),
+		prev: mkSummaries(t, map[string]float64{"food": 40, "gifts": 100}).
+			Summary(bankac.CatAll),
+	}
+
+	type expPair struct {
+		name             string
+		hasThis, hasPrev bool
+	}
+
+	pairs := []expPair{}
+	for _, c := range sp.components() {
+		pairs = append(pairs, expPair{c.name, c.this != nil, c.prev != nil})
+	}
+
+	testhelper.DiffValsReport(t, "components", "pairs", pairs, []expPair{
+		{name: "gifts", hasPrev: true},
+		{name: "food", hasThis: true, hasPrev: true},
+		{name: "travel", hasThis: true},
+		{name: bankac.CatCash, hasThis: true, hasPrev: true},
+		{name: bankac.CatCheque, hasThis: true, hasPrev: true},
+		{name: bankac.CatUnknown, hasThis: true, hasPrev: true},
+	})
+}
+
+func TestCompareReport(t *testing.T) {
+	prog := newProg()
+
+	var out strings.Builder
+
+	prog.out = &out
+
+	s := &summaries{
+		Categoriser: bankac.NewCategoriser(mkSummaries(t,
+			map[string]float64{"food": 50, "travel": 30})),
+		compared: &summaries{
+			Categoriser: bankac.NewCategoriser(mkSummaries(t,
+				map[string]float64{"food": 40, "gifts": 100})),
+		},
+	}
+
+	s.compareReport(prog, bankac.CatAll)
+
+	lines := []string{}
+	for _, line := range strings.Split(out.String(), "\n") {
+		lines = append(lines, strings.TrimRight(line, " "))
+	}
+
+	// a category in only one period shows a blank for the other period
+	// and, if it is not in the compared period, no percentage change
+	testhelper.DiffValsReport(t, "compare report", "lines", lines,
+		[]string{
+			"                 --------Nett---------               %age",
+			"Transaction Type       This   Compared     Change  Change",
+			"================       ====   ========     ======  ======",
+			"all                  -80.00    -140.00      60.00     43%",
+			"    gifts                      -100.00     100.00    100%",
+			"    food             -50.00     -40.00     -10.00    -25%",
+			"    travel           -30.00                -30.00",
+			"",
+		})
+}
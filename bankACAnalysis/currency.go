@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nickwells/personal-utils/bankACAnalysis/bankac"
+)
+
+const (
+	dfltCurrency = "GBP"
+
+	ratesDesc = "exchange rates"
+)
+
+// rate records the exchange rate from one currency to another, starting on
+// the given date
+type rate struct {
+	date time.Time
+	rate float64
+}
+
+// currencyPair identifies the currencies being exchanged
+type currencyPair struct {
+	from, to string
+}
+
+// exchangeRates holds the exchange rates for each pair of currencies in
+// date order
+type exchangeRates map[currencyPair][]rate
+
+// parseRate parses a line from the exchange rates file. The line should
+// have the date (YYYY-MM-DD), the currency being converted from, the
+// currency being converted to and the number of units of the second
+// currency that one unit of the first will buy.
+func parseRate(line string) (currencyPair, rate, error) {
+	const partCount = 4
+
+	parts := strings.Fields(line)
+	if len(parts) != partCount {
+		return currencyPair{}, rate{},
+			fmt.Errorf("there should be %d parts (date, from, to, rate),"+
+				" found %d", partCount, len(parts))
+	}
+
+	date, err := time.Parse(paramDateFormat, parts[0])
+	if err != nil {
+		return currencyPair{}, rate{}, fmt.Errorf("bad date: %s", err)
+	}
+
+	r, err := strconv.ParseFloat(parts[3], 64)
+	if err != nil {
+		return currencyPair{}, rate{}, fmt.Errorf("bad rate: %s", err)
+	}
+
+	if r <= 0 {
+		return currencyPair{}, rate{},
+			fmt.Errorf("bad rate: %g, it must be greater than zero", r)
+	}
+
+	return currencyPair{
+			from: strings.ToUpper(parts[1]),
+			to:   strings.ToUpper(parts[2]),
+		},
+		rate{date: date, rate: r},
+		nil
+}
+
+// readRates reads the exchange rates file. Bad entries are reported and
+// ignored. It returns an error if the file cannot be read.
+func (prog *prog) readRates() error {
+	prog.rates = exchangeRates{}
+
+	if prog.ratesFileName == "" {
+		return nil
+	}
+
+	rf, err := openFile(prog.ratesFileName, ratesDesc)
+	if err != nil {
+		return err
+	}
+	defer rf.Close()
+
+	rScanner := bufio.NewScanner(rf)
+	lineNum := 0
+
+	for rScanner.Scan() {
+		lineNum++
+
+		line := strings.TrimSpace(rScanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		cp, r, err := parseRate(line)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s:%d: Bad exchange rate: %s\n",
+				prog.ratesFileName, lineNum, err)
+
+			continue
+		}
+
+		prog.rates[cp] = append(prog.rates[cp], r)
+	}
+
+	if err := rScanner.Err(); err != nil {
+		return fmt.Errorf("couldn't read the %s file: %w", ratesDesc, err)
+	}
+
+	for _, rates := range prog.rates {
+		sort.SliceStable(rates, func(i, j int) bool {
+			return rates[i].date.Before(rates[j].date)
+		})
+	}
+
+	return nil
+}
+
+// latest returns the most recent of the rates on or before the date
+func latest(rates []rate, date time.Time) (rate, bool) {
+	i := sort.Search(len(rates), func(i int) bool {
+		return rates[i].date.After(date)
+	})
+	if i == 0 {
+		return rate{}, false
+	}
+
+	return rates[i-1], true
+}
+
+// lookup returns the rate to convert from one currency to another on the
+// given date. If there is no direct rate the inverse of the rate in the
+// other direction is used, whichever is the more recent.
+func (er exchangeRates) lookup(
+	from, to string, date time.Time,
+) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+
+	direct, hasDirect := latest(er[currencyPair{from: from, to: to}], date)
+	inverse, hasInverse := latest(er[currencyPair{from: to, to: from}], date)
+
+	switch {
+	case hasDirect && hasInverse:
+		if inverse.date.After(direct.date) {
+			return 1 / inverse.rate, nil
+		}
+
+		return direct.rate, nil
+	case hasDirect:
+		return direct.rate, nil
+	case hasInverse:
+		return 1 / inverse.rate, nil
+	}
+
+	return 0, fmt.Errorf("there is no exchange rate from %s to %s"+
+		" on or before %s", from, to, date.Format(rptDateFormat))
+}
+
+// parseFileCurrency parses a file-currency value which should be a file
+// name pattern and a currency separated by '='
+func parseFileCurrency(val string) (string, string, error) {
+	pattern, ccy, ok := strings.Cut(val, "=")
+	if !ok || pattern == "" || ccy == "" {
+		return "", "", fmt.Errorf("bad file currency %q:"+
+			" it should be pattern=currency", val)
+	}
+
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return "", "", fmt.Errorf("bad file currency %q: %w", val, err)
+	}
+
+	return pattern, strings.ToUpper(ccy), nil
+}
+
+// currencyOf returns the currency of the named file. The first matching
+// file-currency pattern is used, it is matched against both the full name
+// and the base name of the file. If no pattern matches the default
+// currency is used.
+func (prog *prog) currencyOf(name string) string {
+	for _, fc := range prog.fileCurrencies {
+		pattern, ccy, err := parseFileCurrency(fc)
+		if err != nil {
+			continue
+		}
+
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return ccy
+		}
+
+		if ok, _ := filepath.Match(pattern, filepath.Base(name)); ok {
+			return ccy
+		}
+	}
+
+	return prog.currency
+}
+
+// convert converts the transaction amounts into the reporting currency,
+// keeping the original amounts. The balance is left in the original
+// currency.
+func (prog *prog) convert(xa *Xactn) error {
+	xa.OrigDebitAmt, xa.OrigCreditAmt = xa.DebitAmt, xa.CreditAmt
+
+	r, err := prog.rates.lookup(xa.Currency, prog.reportCurrency, xa.Date)
+	if err != nil {
+		return err
+	}
+
+	xa.DebitAmt *= r
+	xa.CreditAmt *= r
+
+	return nil
+}
+
+// setCurrencies sets the currency of each transaction without one and
+// converts its amounts into the reporting currency. Transactions which
+// cannot be converted are reported and removed.
+func (prog *prog) setCurrencies(name string, xas []Xactn) []Xactn {
+	fileCcy := prog.currencyOf(name)
+	converted := make([]Xactn, 0, len(xas))
+
+	for _, xa := range xas {
+		if xa.Currency == "" {
+			xa.Currency = fileCcy
+		}
+
+		if err := prog.convert(&xa); err != nil {
+			fmt.Fprintf(os.Stderr, "%s:%d: %s\n", xa.FileName, xa.LineNum, err)
+			continue
+		}
+
+		converted = append(converted, xa)
+	}
+
+	return converted
+}
+
+// currencyTotals returns the nett amounts of the Summary's transactions in
+// each of their original currencies
+func currencyTotals(s *bankac.Summary) string {
+	totals := []string{}
+
+	for _, ccy := range s.Currencies() {
+		a := s.ByCurrency[ccy]
+		totals = append(totals,
+			fmt.Sprintf("%s %.2f", ccy, a.CreditAmt-a.DebitAmt))
+	}
+
+	return strings.Join(totals, ", ")
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+// mkDate returns the date for the given year, month and day
+func mkDate(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestLookup(t *testing.T) {
+	er := exchangeRates{
+		{from: "EUR", to: "GBP"}: {
+			{date: mkDate(2020, time.January, 1), rate: 0.8},
+			{date: mkDate(2023, time.January, 1), rate: 0.9},
+		},
+		{from: "GBP", to: "EUR"}: {
+			{date: mkDate(2024, time.January, 1), rate: 1.25},
+		},
+	}
+
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		from, to string
+		date     time.Time
+		expRate  float64
+	}{
+		{
+			ID:      testhelper.MkID("same currency"),
+			from:    "USD",
+			to:      "USD",
+			date:    mkDate(2019, time.January, 1),
+			expRate: 1,
+		},
+		{
+			ID:      testhelper.MkID("direct rate, first day"),
+			from:    "EUR",
+			to:      "GBP",
+			date:    mkDate(2020, time.January, 1),
+			expRate: 0.8,
+		},
+		{
+			ID:      testhelper.MkID("direct rate, later rate"),
+			from:    "EUR",
+			to:      "GBP",
+			date:    mkDate(2023, time.June, 1),
+			expRate: 0.9,
+		},
+		{
+			ID:      testhelper.MkID("inverse rate is more recent"),
+			from:    "EUR",
+			to:      "GBP",
+			date:    mkDate(2024, time.June, 1),
+			expRate: 0.8,
+		},
+		{
+			ID:      testhelper.MkID("inverse rate only"),
+			from:    "GBP",
+			to:      "EUR",
+			date:    mkDate(2021, time.June, 1),
+			expRate: 1.25,
+		},
+		{
+			ID: testhelper.MkID("before the first rate"),
+			ExpErr: testhelper.MkExpErr(
+				"there is no exchange rate from EUR to GBP"),
+			from: "EUR",
+			to:   "GBP",
+			date: mkDate(2019, time.December, 31),
+		},
+	}
+
+	for _, tc := range testCases {
+		r, err := er.lookup(tc.from, tc.to, tc.date)
+		if testhelper.CheckExpErr(t, err, tc) && err == nil {
+			testhelper.DiffFloat(t, tc.IDStr(), "rate", r, tc.expRate, 1e-9)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+const (
+	// paramDateFormat is the format of dates given as parameters
+	paramDateFormat = "2006-01-02"
+	// rptDateFormat is the format of dates shown in the reports
+	rptDateFormat = "2006-Jan-02"
+
+	// taxYearPrefix introduces the name of a UK tax year
+	taxYearPrefix = "tax-year-"
+	// taxYearStartMonth and taxYearStartDay give the start of the UK tax
+	// year
+	taxYearStartMonth = time.April
+	taxYearStartDay   = 6
+)
+
+var (
+	yearRE    = regexp.MustCompile(`^([0-9]{4})$`)
+	quarterRE = regexp.MustCompile(`^([0-9]{4})-[qQ]([1-4])$`)
+	monthRE   = regexp.MustCompile(`^([0-9]{4})-([0-9]{2})$`)
+	taxYearRE = regexp.MustCompile(`^` + taxYearPrefix + `([0-9]{4})$`)
+)
+
+// dateRange represents the range of dates for which transactions are
+// reported. A zero from or to date means that the range is unbounded at that
+// end. Both the from and to dates are included in the range.
+type dateRange struct {
+	name string
+	from time.Time
+	to   time.Time
+}
+
+// isSet returns true if the range has either bound set
+func (dr dateRange) isSet() bool {
+	return !dr.from.IsZero() || !dr.to.IsZero()
+}
+
+// contains returns true if the date is in the range
+func (dr dateRange) contains(t time.Time) bool {
+	if !dr.from.IsZero() && t.Before(dr.from) {
+		return false
+	}
+
+	if !dr.to.IsZero() && t.After(dr.to) {
+		return false
+	}
+
+	return true
+}
+
+// String returns a description of the date range
+func (dr dateRange) String() string {
+	var s string
+
+	switch {
+	case !dr.from.IsZero() && !dr.to.IsZero():
+		s = dr.from.Format(rptDateFormat) + " to " + dr.to.Format(rptDateFormat)
+	case !dr.from.IsZero():
+		s = "from " + dr.from.Format(rptDateFormat)
+	case !dr.to.IsZero():
+		s = "up to " + dr.to.Format(rptDateFormat)
+	default:
+		s = "all dates"
+	}
+
+	if dr.name != "" {
+		s += " (" + dr.name + ")"
+	}
+
+	return s
+}
+
+// check returns a non-nil error if the range is empty
+func (dr dateRange) check() error {
+	if !dr.from.IsZero() && !dr.to.IsZero() && dr.to.Before(dr.from) {
+		return errors.New("the date range is empty: " + dr.String())
+	}
+
+	return nil
+}
+
+// parseNamedPeriod converts the name of a period into the corresponding
+// date range. The name can be a year (YYYY), a quarter (YYYY-Qn), a month
+// (YYYY-MM) or a UK tax year (tax-year-YYYY) which starts on the 6th of
+// April in the given year.
+func parseNamedPeriod(name string) (dateRange, error) {
+	dr := dateRange{name: name}
+
+	if m := yearRE.FindStringSubmatch(name); m != nil {
+		y, _ := strconv.Atoi(m[1])
+		dr.from = time.Date(y, time.January, 1, 0, 0, 0, 0, time.UTC)
+		dr.to = dr.from.AddDate(1, 0, -1)
+		dr.name = "year " + name
+
+		return dr, nil
+	}
+
+	if m := quarterRE.FindStringSubmatch(name); m != nil {
+		y, _ := strconv.Atoi(m[1])
+		q, _ := strconv.Atoi(m[2])
+		dr.from = time.Date(y, time.Month(3*q-2), 1, 0, 0, 0, 0, time.UTC)
+		dr.to = dr.from.AddDate(0, 3, -1)
+
+		return dr, nil
+	}
+
+	if m := monthRE.FindStringSubmatch(name); m != nil {
+		y, _ := strconv.Atoi(m[1])
+		mon, _ := strconv.Atoi(m[2])
+
+		if mon < 1 || mon > 12 {
+			return dateRange{}, fmt.Errorf("bad month in period %q", name)
+		}
+
+		dr.from = time.Date(y, time.Month(mon), 1, 0, 0, 0, 0, time.UTC)
+		dr.to = dr.from.AddDate(0, 1, -1)
+
+		return dr, nil
+	}
+
+	if m := taxYearRE.FindStringSubmatch(name); m != nil {
+		y, _ := strconv.Atoi(m[1])
+		dr.from = time.Date(y, taxYearStartMonth, taxYearStartDay,
+			0, 0, 0, 0, time.UTC)
+		dr.to = dr.from.AddDate(1, 0, -1)
+		dr.name = fmt.Sprintf("tax year %d/%02d", y, (y+1)%100)
+
+		return dr, nil
+	}
+
+	return dateRange{},
+		fmt.Errorf("unknown period %q: it should be a year (YYYY),"+
+			" a quarter (YYYY-Qn), a month (YYYY-MM)"+
+			" or a tax year ("+taxYearPrefix+"YYYY)",
+			name)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestParseNamedPeriod(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		name  string
+		expDR dateRange
+	}{
+		{
+			ID:   testhelper.MkID("year"),
+			name: "2024",
+			expDR: dateRange{
+				name: "year 2024",
+				from: mkDate(2024, time.January, 1),
+				to:   mkDate(2024, time.December, 31),
+			},
+		},
+		{
+			ID:   testhelper.MkID("first quarter"),
+			name: "2024-q1",
+			expDR: dateRange{
+				name: "2024-q1",
+				from: mkDate(2024, time.January, 1),
+				to:   mkDate(2024, time.March, 31),
+			},
+		},
+		{
+			ID:   testhelper.MkID("last quarter"),
+			name: "2024-Q4",
+			expDR: dateRange{
+				name: "2024-Q4",
+				from: mkDate(2024, time.October, 1),
+				to:   mkDate(2024, time.December, 31),
+			},
+		},
+		{
+			ID:   testhelper.MkID("February in a leap year"),
+			name: "2024-02",
+			expDR: dateRange{
+				name: "2024-02",
+				from: mkDate(2024, time.February, 1),
+				to:   mkDate(2024, time.February, 29),
+			},
+		},
+		{
+			ID:   testhelper.MkID("December"),
+			name: "2023-12",
+			expDR: dateRange{
+				name: "2023-12",
+				from: mkDate(2023, time.December, 1),
+				to:   mkDate(2023, time.December, 31),
+			},
+		},
+		{
+			ID:   testhelper.MkID("tax year"),
+			name: "tax-year-2023",
+			expDR: dateRange{
+				name: "tax year 2023/24",
+				from: mkDate(2023, time.April, 6),
+				to:   mkDate(2024, time.April, 5),
+			},
+		},
+		{
+			ID:   testhelper.MkID("tax year ending in a new century"),
+			name: "tax-year-2099",
+			expDR: dateRange{
+				name: "tax year 2099/00",
+				from: mkDate(2099, time.April, 6),
+				to:   mkDate(2100, time.April, 5),
+			},
+		},
+		{
+			ID:     testhelper.MkID("bad month"),
+			ExpErr: testhelper.MkExpErr(`bad month in period "2024-13"`),
+			name:   "2024-13",
+		},
+		{
+			ID:     testhelper.MkID("month zero"),
+			ExpErr: testhelper.MkExpErr(`bad month in period "2024-00"`),
+			name:   "2024-00",
+		},
+		{
+			ID:     testhelper.MkID("bad quarter"),
+			ExpErr: testhelper.MkExpErr(`unknown period "2024-Q5"`),
+			name:   "2024-Q5",
+		},
+		{
+			ID:     testhelper.MkID("unknown"),
+			ExpErr: testhelper.MkExpErr(`unknown period "last-year"`),
+			name:   "last-year",
+		},
+	}
+
+	for _, tc := range testCases {
+		dr, err := parseNamedPeriod(tc.name)
+		if testhelper.CheckExpErr(t, err, tc) && err == nil {
+			testhelper.DiffValsReport(t, tc.IDStr(), "date range",
+				dr, tc.expDR)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 /*
-bankACAnalysis will take a file of transactions (comma-separated values) and
-arrange them into a tree of types such as entertainment, food, utility bills
-etc.
+bankACAnalysis will take files of bank account transactions and arrange them
+into a tree of types such as entertainment, food, utility bills etc. The
+files may be comma-separated values (CSV), Open Financial Exchange (OFX or
+QFX) or Quicken Interchange Format (QIF) files.
 */
 package main
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// xactnKey holds the values which identify a transaction. Two transactions
+// from different files with the same key are taken to be the same
+// transaction. The account is part of the key so that the same payment made
+// from two accounts is not taken as a duplicate.
+type xactnKey struct {
+	account   string
+	date      string
+	xaType    string
+	desc      string
+	debitAmt  float64
+	creditAmt float64
+	balance   float64
+}
+
+// keyOf returns the identifying values of the transaction
+func keyOf(xa Xactn) xactnKey {
+	return xactnKey{
+		account:   xa.Account,
+		date:      xa.Date.Format(paramDateFormat),
+		xaType:    xa.Type,
+		desc:      xa.Desc,
+		debitAmt:  xa.DebitAmt,
+		creditAmt: xa.CreditAmt,
+		balance:   xa.Balance,
+	}
+}
+
+// filePair identifies a pair of files; the first file is the one from which
+// the transaction was taken and the second is the one where it was
+// duplicated
+type filePair struct {
+	first, second string
+}
+
+// overlap records the duplicate transactions found in a pair of files
+type overlap struct {
+	count     int
+	firstDate time.Time
+	lastDate  time.Time
+}
+
+// add records the duplicate transaction in the overlap
+func (o *overlap) add(xa Xactn) {
+	if o.count == 0 || xa.Date.Before(o.firstDate) {
+		o.firstDate = xa.Date
+	}
+
+	if o.count == 0 || xa.Date.After(o.lastDate) {
+		o.lastDate = xa.Date
+	}
+
+	o.count++
+}
+
+// removeDuplicates returns the transactions with any that are repeated in a
+// later file removed. A transaction is only taken as a duplicate if it
+// appears in a different file; identical transactions in the same file are
+// all kept. The overlaps are returned with the files in the order given.
+func removeDuplicates(files []string, byFile map[string][]Xactn) (
+	[]Xactn, []filePair, map[filePair]*overlap,
+) {
+	// seen records, for each key, the number of times it appears in each of
+	// the files processed so far
+	seen := map[xactnKey]map[string]int{}
+	pairs := []filePair{}
+	overlaps := map[filePair]*overlap{}
+	kept := []Xactn{}
+
+	for _, name := range files {
+		for _, xa := range byFile[name] {
+			k := keyOf(xa)
+
+			counts, ok := seen[k]
+			if !ok {
+				counts = map[string]int{}
+				seen[k] = counts
+			}
+
+			counts[name]++
+
+			if dupOf := earlierFileWith(files, name, counts); dupOf != "" {
+				fp := filePair{first: dupOf, second: name}
+
+				o, ok := overlaps[fp]
+				if !ok {
+					o = &overlap{}
+					overlaps[fp] = o
+					pairs = append(pairs, fp)
+				}
+
+				o.add(xa)
+
+				continue
+			}
+
+			kept = append(kept, xa)
+		}
+	}
+
+	return kept, pairs, overlaps
+}
+
+// earlierFileWith returns the first file, preceding the named file, which
+// has at least as many instances of the transaction as have been seen so far
+// in the named file. It returns the empty string if there is no such file.
+func earlierFileWith(
+	files []string, name string, counts map[string]int,
+) string {
+	n := counts[name]
+
+	for _, f := range files {
+		if f == name {
+			break
+		}
+
+		if counts[f] >= n {
+			return f
+		}
+	}
+
+	return ""
+}
+
+// reportOverlaps reports the duplicate transactions found for each pair of
+// files
+func reportOverlaps(pairs []filePair, overlaps map[filePair]*overlap) {
+	if len(pairs) == 0 {
+		return
+	}
+
+	fmt.Fprintln(os.Stderr, "Overlapping transactions (each is counted once):")
+
+	for _, fp := range pairs {
+		o := overlaps[fp]
+		fmt.Fprintf(os.Stderr,
+			"    %s repeats %d transaction(s) from %s: %s to %s\n",
+			fp.second, o.count, fp.first,
+			o.firstDate.Format(rptDateFormat),
+			o.lastDate.Format(rptDateFormat))
+	}
+
+	fmt.Fprintln(os.Stderr)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nickwells/personal-utils/bankACAnalysis/bankac"
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+// mkFileXactns returns the transactions for the file, one for each of the
+// days given, each with the same description and amount
+func mkFileXactns(fileName string, days ...int) []Xactn {
+	xas := []Xactn{}
+
+	for i, d := range days {
+		xas = append(xas, Xactn{Xactn: bankac.Xactn{
+			FileName: fileName,
+			LineNum:  i + 1,
+			Date:     mkDate(2024, time.March, d),
+			Desc:     "TESCO",
+			DebitAmt: 10,
+		}})
+	}
+
+	return xas
+}
+
+func TestRemoveDuplicates(t *testing.T) {
+	type expOverlap struct {
+		first, second string
+		count         int
+		firstDay      int
+		lastDay       int
+	}
+
+	testCases := []struct {
+		testhelper.ID
+		byFile      map[string][]Xactn
+		expKept     []string
+		expOverlaps []expOverlap
+	}{
+		{
+			ID: testhelper.MkID("overlapping files"),
+			byFile: map[string][]Xactn{
+				"a": mkFileXactns("a", 1, 2, 3),
+				"b": mkFileXactns("b", 2, 3, 4),
+			},
+			expKept: []string{"a:1", "a:2", "a:3", "b:3"},
+			expOverlaps: []expOverlap{
+				{first: "a", second: "b", count: 2, firstDay: 2, lastDay: 3},
+			},
+		},
+		{
+			ID: testhelper.MkID("repeats within a file"),
+			byFile: map[string][]Xactn{
+				"a": mkFileXactns("a", 1, 1),
+				"b": mkFileXactns("b", 1, 1, 1),
+			},
+			expKept: []string{"a:1", "a:2", "b:3"},
+			expOverlaps: []expOverlap{
+				{first: "a", second: "b", count: 2, firstDay: 1, lastDay: 1},
+			},
+		},
+		{
+			ID: testhelper.MkID("chain across three files"),
+			byFile: map[string][]Xactn{
+				"a": mkFileXactns("a", 1, 2),
+				"b": mkFileXactns("b", 2, 3),
+				"c": mkFileXactns("c", 2, 3, 4),
+			},
+			expKept: []string{"a:1", "a:2", "b:2", "c:3"},
+			expOverlaps: []expOverlap{
+				{first: "a", second: "b", count: 1, firstDay: 2, lastDay: 2},
+				{first: "a", second: "c", count: 1, firstDay: 2, lastDay: 2},
+				{first: "b", second: "c", count: 1, firstDay: 3, lastDay: 3},
+			},
+		},
+		{
+			ID: testhelper.MkID("no overlap"),
+			byFile: map[string][]Xactn{
+				"a": mkFileXactns("a", 1),
+				"b": mkFileXactns("b", 2),
+			},
+			expKept:     []string{"a:1", "b:1"},
+			expOverlaps: []expOverlap{},
+		},
+	}
+
+	for _, tc := range testCases {
+		kept, pairs, overlaps := removeDuplicates(
+			[]string{"a", "b", "c"}, tc.byFile)
+
+		keptLocs := []string{}
+		for _, xa := range kept {
+			keptLocs = append(keptLocs, xa.Location())
+		}
+
+		testhelper.DiffValsReport(t, tc.IDStr(), "kept",
+			keptLocs, tc.expKept)
+
+		ovs := []expOverlap{}
+		for _, fp := range pairs {
+			o := overlaps[fp]
+			ovs = append(ovs, expOverlap{
+				first:    fp.first,
+				second:   fp.second,
+				count:    o.count,
+				firstDay: o.firstDate.Day(),
+				lastDay:  o.lastDate.Day(),
+			})
+		}
+
+		testhelper.DiffValsReport(t, tc.IDStr(), "overlaps",
+			ovs, tc.expOverlaps)
+	}
+}
+
+func TestEarlierFileWith(t *testing.T) {
+	files := []string{"a", "b", "c"}
+
+	testCases := []struct {
+		testhelper.ID
+		name    string
+		counts  map[string]int
+		expFile string
+	}{
+		{
+			ID:     testhelper.MkID("first file"),
+			name:   "a",
+			counts: map[string]int{"a": 1, "b": 1},
+		},
+		{
+			ID:      testhelper.MkID("in the first file"),
+			name:    "c",
+			counts:  map[string]int{"a": 1, "b": 1, "c": 1},
+			expFile: "a",
+		},
+		{
+			ID:      testhelper.MkID("only the second file has enough"),
+			name:    "c",
+			counts:  map[string]int{"a": 1, "b": 2, "c": 2},
+			expFile: "b",
+		},
+		{
+			ID:     testhelper.MkID("more than in any earlier file"),
+			name:   "c",
+			counts: map[string]int{"a": 1, "b": 2, "c": 3},
+		},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffString(t, tc.IDStr(), "file",
+			earlierFileWith(files, tc.name, tc.counts), tc.expFile)
+	}
+}
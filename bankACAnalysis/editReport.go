@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/nickwells/col.mod/v6/col"
+	"github.com/nickwells/col.mod/v6/colfmt"
+)
+
+// editStat records the use made of an edit while normalising the
+// transaction descriptions
+type editStat struct {
+	// matches counts the descriptions that the edit matched
+	matches int
+	// shadowedBy is the line of an earlier edit which changed a description
+	// that this edit would otherwise have matched
+	shadowedBy int
+	// unmapped holds the descriptions, last changed by this edit, which are
+	// not in the map
+	unmapped []string
+
+	// changed counts the transactions whose description the edit changed
+	// and debitAmt and creditAmt are their total amounts; originals records
+	// their distinct original descriptions
+	changed   int
+	debitAmt  float64
+	creditAmt float64
+	originals map[string]bool
+}
+
+// addUnmapped records the description as an unmapped result of the edit
+func (es *editStat) addUnmapped(desc string) {
+	if !slices.Contains(es.unmapped, desc) {
+		es.unmapped = append(es.unmapped, desc)
+	}
+}
+
+// add records the transaction as one whose description the edit changed
+func (es *editStat) add(xa Xactn) {
+	if es.originals == nil {
+		es.originals = map[string]bool{}
+	}
+
+	es.changed++
+	es.debitAmt += xa.DebitAmt
+	es.creditAmt += xa.CreditAmt
+	es.originals[xa.OrigDesc] = true
+}
+
+// editReport will report, for each edit in the order given in the edit
+// file, how many transactions it changed, how many distinct original
+// descriptions they had and their total amounts
+func (s *summaries) editReport(prog *prog) {
+	const (
+		floatColWidth = 10
+		floatColPrec  = 2
+		countColWidth = 5
+	)
+
+	searchWidth := len("Search")
+	replWidth := len("Replacement")
+
+	for _, ed := range s.Edits {
+		searchWidth = max(searchWidth, len(ed.Search))
+		replWidth = max(replWidth, len(ed.Replacement))
+	}
+
+	floatCol := colfmt.Float{
+		W:    floatColWidth,
+		Prec: floatColPrec,
+		Zeroes: &colfmt.FloatZeroHandler{
+			Handle:  true,
+			Replace: "",
+		},
+	}
+
+	rpt := col.NewReportOrPanic(col.NewHeaderOrPanic(), prog.out,
+		col.New(&colfmt.Int{W: countColWidth}, "Line"),
+		col.New(&colfmt.String{W: searchWidth}, "Search"),
+		col.New(&colfmt.String{W: replWidth}, "Replacement"),
+		col.New(&colfmt.Int{W: countColWidth}, "Count"),
+		col.New(&colfmt.Int{W: countColWidth}, "Distinct", "Originals"),
+		col.New(&floatCol, "Debit", "Amount"),
+		col.New(&floatCol, "Credit", "Amount"),
+	)
+
+	for i, ed := range s.Edits {
+		es := s.editStats[i]
+
+		err := rpt.PrintRow(
+			ed.LineNum,
+			ed.Search,
+			ed.Replacement,
+			es.changed,
+			len(es.originals),
+			es.debitAmt,
+			es.creditAmt)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Couldn't print the row:", err)
+		}
+	}
+}
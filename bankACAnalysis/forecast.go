@@ -0,0 +1,386 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nickwells/col.mod/v6/col"
+	"github.com/nickwells/col.mod/v6/colfmt"
+	"github.com/nickwells/personal-utils/bankACAnalysis/bankac"
+)
+
+const (
+	dfltForecastMonths = 3
+
+	plannedDesc = "planned items"
+
+	lowBalanceFlag = "LOW"
+)
+
+// forecastItem is a payment or receipt expected on a future date; the
+// amount is positive for a receipt and negative for a payment
+type forecastItem struct {
+	date   time.Time
+	desc   string
+	amount float64
+}
+
+// parsePlannedItem parses a line from the planned items file. The line
+// should have the date, the amount and the description separated by
+// spaces. Payments should have a negative amount.
+func parsePlannedItem(line string) (forecastItem, error) {
+	const minParts = 3
+
+	parts := strings.Fields(line)
+	if len(parts) < minParts {
+		return forecastItem{},
+			fmt.Errorf("there should be %d parts (date, amount,"+
+				" description), found %d", minParts, len(parts))
+	}
+
+	date, err := time.Parse(paramDateFormat, parts[0])
+	if err != nil {
+		return forecastItem{}, fmt.Errorf("bad date: %s", err)
+	}
+
+	amt, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return forecastItem{}, fmt.Errorf("bad amount: %s", err)
+	}
+
+	return forecastItem{
+		date:   date,
+		desc:   strings.Join(parts[2:], " "),
+		amount: amt,
+	}, nil
+}
+
+// readPlannedItems reads the planned items file, if it is given. Bad
+// entries are reported and ignored. It returns an error if the file cannot
+// be read.
+func (prog *prog) readPlannedItems() ([]forecastItem, error) {
+	items := []forecastItem{}
+
+	if prog.plannedFileName == "" {
+		return items, nil
+	}
+
+	pf, err := openFile(prog.plannedFileName, plannedDesc)
+	if err != nil {
+		return nil, err
+	}
+	defer pf.Close()
+
+	pScanner := bufio.NewScanner(pf)
+	lineNum := 0
+
+	for pScanner.Scan() {
+		lineNum++
+
+		line := strings.TrimSpace(pScanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		item, err := parsePlannedItem(line)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s:%d: Bad planned item: %s\n",
+				prog.plannedFileName, lineNum, err)
+
+			continue
+		}
+
+		items = append(items, item)
+	}
+
+	if err := pScanner.Err(); err != nil {
+		return nil, fmt.Errorf("couldn't read the %s file: %w",
+			plannedDesc, err)
+	}
+
+	return items, nil
+}
+
+// accountKey returns the key identifying the account of the transaction.
+// Files which do not give the account are taken to be exports of the same
+// account if they have the same currency.
+func accountKey(xa Xactn) string {
+	if xa.Account == "" {
+		return "currency: " + xa.Currency
+	}
+
+	return "account: " + xa.Account
+}
+
+// acctBalance holds the latest balance of an account, converted into the
+// reporting currency, and the date of the transaction giving it
+type acctBalance struct {
+	balance float64
+	date    time.Time
+}
+
+// latestBalances returns the latest balance of each account, keyed by the
+// accountKey, and the date of the latest transaction. The balance of an
+// account which cannot be converted into the reporting currency is
+// reported and left out.
+func (s *summaries) latestBalances(
+	prog *prog,
+) (map[string]acctBalance, time.Time) {
+	byFile := map[string][]Xactn{}
+
+	var lastDate time.Time
+
+	for _, xa := range s.xactns {
+		byFile[xa.FileName] = append(byFile[xa.FileName], xa)
+
+		if xa.Date.After(lastDate) {
+			lastDate = xa.Date
+		}
+	}
+
+	byAccount := map[string]Xactn{}
+
+	for _, xas := range byFile {
+		var (
+			latest Xactn
+			found  bool
+		)
+
+		for _, xa := range inDateOrder(xas) {
+			if xa.HasBalance {
+				latest, found = xa, true
+			}
+		}
+
+		if !found {
+			continue
+		}
+
+		acct := accountKey(latest)
+		if prev, ok := byAccount[acct]; !ok || latest.Date.After(prev.Date) {
+			byAccount[acct] = latest
+		}
+	}
+
+	balances := map[string]acctBalance{}
+
+	for acct, xa := range byAccount {
+		r, err := prog.rates.lookup(xa.Currency, prog.reportCurrency, xa.Date)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s:%d: Can't convert the balance: %s\n",
+				xa.FileName, xa.LineNum, err)
+
+			continue
+		}
+
+		balances[acct] = acctBalance{balance: xa.Balance * r, date: xa.Date}
+	}
+
+	return balances, lastDate
+}
+
+// projectRecurring returns the expected occurrences of the recurring
+// payment, or receipt, after the start date and up to the end date. A
+// payment whose next occurrence was due more than one period before the
+// start date is taken to have stopped and nothing is returned.
+func projectRecurring(r recurring, start, end time.Time) []forecastItem {
+	items := []forecastItem{}
+
+	d := r.freq.next(r.lastDate)
+	if r.freq.next(d).Before(start) {
+		return items
+	}
+
+	amt := r.lastAmt
+	if !r.credit {
+		amt = -amt
+	}
+
+	for ; !d.After(end); d = r.freq.next(d) {
+		if d.After(start) {
+			items = append(items,
+				forecastItem{date: d, desc: r.desc, amount: amt})
+		}
+	}
+
+	return items
+}
+
+// recurringForecast returns the expected occurrences of the recurring
+// payments and receipts of each account up to the end date. They are
+// projected from the date of the latest balance of the account, or from
+// the start date if it has none, so that the items expected between an
+// account's latest balance and the start date are included.
+func (s *summaries) recurringForecast(
+	prog *prog, balances map[string]acctBalance, start, end time.Time,
+) []forecastItem {
+	byAcct := map[string][]Xactn{}
+
+	xas := bankac.MergeParts(s.categoryXactns(bankac.CatAll, listByDate))
+	for _, xa := range xas {
+		byAcct[accountKey(xa)] = append(byAcct[accountKey(xa)], xa)
+	}
+
+	items := []forecastItem{}
+
+	for acct, xas := range byAcct {
+		from := start
+		if ab, ok := balances[acct]; ok {
+			from = ab.date
+		}
+
+		for _, credit := range []bool{true, false} {
+			for _, r := range findAllRecurring(prog, xas, credit) {
+				items = append(items, projectRecurring(r, from, end)...)
+			}
+		}
+	}
+
+	return items
+}
+
+// forecastReport will project the balance forward, day by day, from the
+// latest balance of the accounts, using the recurring payments and receipts
+// and any planned items. The items expected between an account's latest
+// balance and the start of the forecast are added to the opening balance.
+// Each day is shown with the items expected on it and the balance at the
+// end of the day and any balance, including the opening balance, which is
+// below the threshold is flagged. It returns an error if the planned items
+// cannot be read.
+func (s *summaries) forecastReport(prog *prog) error {
+	const (
+		floatColWidth = 10
+		floatColPrec  = 2
+		openingDesc   = "opening balance"
+	)
+
+	balances, start := s.latestBalances(prog)
+	if start.IsZero() {
+		fmt.Fprintln(prog.out, "There are no transactions to forecast from")
+		return nil
+	}
+
+	if len(balances) == 0 {
+		fmt.Fprintln(prog.out,
+			"No balances are known, the forecast starts from zero")
+	}
+
+	end := start.AddDate(0, prog.forecastMonths, 0)
+
+	planned, err := prog.readPlannedItems()
+	if err != nil {
+		return err
+	}
+
+	balance := 0.0
+	for _, ab := range balances {
+		balance += ab.balance
+	}
+
+	byDay := map[string][]forecastItem{}
+	gapItems := 0
+
+	for _, item := range s.recurringForecast(prog, balances, start, end) {
+		if !item.date.After(start) {
+			balance += item.amount
+			gapItems++
+
+			continue
+		}
+
+		day := item.date.Format(paramDateFormat)
+		byDay[day] = append(byDay[day], item)
+	}
+
+	for _, item := range planned {
+		if item.date.After(start) && !item.date.After(end) {
+			day := item.date.Format(paramDateFormat)
+			byDay[day] = append(byDay[day], item)
+		}
+	}
+
+	fmt.Fprintf(prog.out, "Opening balance: %.2f on %s\n",
+		balance, start.Format(rptDateFormat))
+
+	if gapItems > 0 {
+		fmt.Fprintf(prog.out, "This includes %d item(s) expected since"+
+			" the latest balance of an account\n", gapItems)
+	}
+
+	fmt.Fprintf(prog.out, "Forecast to: %s, threshold: %.2f\n\n",
+		end.Format(rptDateFormat), prog.forecastThreshold)
+
+	descs := map[string]string{}
+	descWidth := max(len("Description"), len(openingDesc))
+
+	for day, items := range byDay {
+		slices.SortStableFunc(items, func(a, b forecastItem) int {
+			return cmp.Compare(a.desc, b.desc)
+		})
+
+		names := []string{}
+		for _, item := range items {
+			names = append(names, item.desc)
+		}
+
+		descs[day] = strings.Join(names, ", ")
+		descWidth = max(descWidth, len(descs[day]))
+	}
+
+	floatCol := colfmt.Float{W: floatColWidth, Prec: floatColPrec}
+
+	rpt := col.NewReportOrPanic(col.NewHeaderOrPanic(), prog.out,
+		col.New(&colfmt.Time{Format: rptDateFormat}, "Date"),
+		col.New(&colfmt.String{W: descWidth}, "Description"),
+		col.New(&floatCol, "Amount"),
+		col.New(&floatCol, "Balance"),
+		col.New(&colfmt.String{W: len(lowBalanceFlag)}, ""),
+	)
+
+	lowest, lowestDate := balance, start
+
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		day := d.Format(paramDateFormat)
+		desc := descs[day]
+
+		var amount any = col.Skip{}
+
+		switch {
+		case d.Equal(start):
+			desc = openingDesc
+		case len(byDay[day]) > 0:
+			total := 0.0
+			for _, item := range byDay[day] {
+				total += item.amount
+			}
+
+			balance += total
+			amount = total
+		}
+
+		flag := ""
+		if balance < prog.forecastThreshold {
+			flag = lowBalanceFlag
+		}
+
+		if balance < lowest {
+			lowest, lowestDate = balance, d
+		}
+
+		err := rpt.PrintRow(d, desc, amount, balance, flag)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Couldn't print the row:", err)
+		}
+	}
+
+	fmt.Fprintf(prog.out, "\nLowest balance: %.2f on %s\n",
+		lowest, lowestDate.Format(rptDateFormat))
+
+	return nil
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nickwells/personal-utils/bankACAnalysis/bankac"
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestParsePlannedItem(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		line    string
+		expItem forecastItem
+	}{
+		{
+			ID:   testhelper.MkID("good"),
+			line: "2024-03-15 -250.50 car   insurance",
+			expItem: forecastItem{
+				date:   mkDate(2024, time.March, 15),
+				desc:   "car insurance",
+				amount: -250.5,
+			},
+		},
+		{
+			ID:     testhelper.MkID("too few parts"),
+			ExpErr: testhelper.MkExpErr("there should be 3 parts"),
+			line:   "2024-03-15 -250.50",
+		},
+		{
+			ID:     testhelper.MkID("bad date"),
+			ExpErr: testhelper.MkExpErr("bad date"),
+			line:   "15/03/2024 -250.50 car insurance",
+		},
+		{
+			ID:     testhelper.MkID("bad amount"),
+			ExpErr: testhelper.MkExpErr("bad amount"),
+			line:   "2024-03-15 lots car insurance",
+		},
+	}
+
+	for _, tc := range testCases {
+		item, err := parsePlannedItem(tc.line)
+		if testhelper.CheckExpErr(t, err, tc) && err == nil {
+			testhelper.DiffValsReport(t, tc.IDStr(), "planned item",
+				item, tc.expItem)
+		}
+	}
+}
+
+func TestProjectRecurring(t *testing.T) {
+	monthly := frequencies[2]
+	start := mkDate(2024, time.March, 10)
+	end := start.AddDate(0, dfltForecastMonths, 0)
+
+	testCases := []struct {
+		testhelper.ID
+		r        recurring
+		expItems []forecastItem
+	}{
+		{
+			ID: testhelper.MkID("payment"),
+			r: recurring{
+				desc:     "RENT",
+				freq:     monthly,
+				lastAmt:  500,
+				lastDate: mkDate(2024, time.March, 1),
+			},
+			expItems: []forecastItem{
+				{date: mkDate(2024, time.April, 1), desc: "RENT", amount: -500},
+				{date: mkDate(2024, time.May, 1), desc: "RENT", amount: -500},
+				{date: mkDate(2024, time.June, 1), desc: "RENT", amount: -500},
+			},
+		},
+		{
+			ID: testhelper.MkID("receipt, one period overdue"),
+			r: recurring{
+				desc:     "PAY",
+				credit:   true,
+				freq:     monthly,
+				lastAmt:  2000,
+				lastDate: mkDate(2024, time.January, 25),
+			},
+			expItems: []forecastItem{
+				{date: mkDate(2024, time.March, 25), desc: "PAY", amount: 2000},
+				{date: mkDate(2024, time.April, 25), desc: "PAY", amount: 2000},
+				{date: mkDate(2024, time.May, 25), desc: "PAY", amount: 2000},
+			},
+		},
+		{
+			ID: testhelper.MkID("stopped"),
+			r: recurring{
+				desc:     "GYM",
+				freq:     monthly,
+				lastAmt:  30,
+				lastDate: mkDate(2023, time.December, 1),
+			},
+			expItems: []forecastItem{},
+		},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffValsReport(t, tc.IDStr(), "items",
+			projectRecurring(tc.r, start, end), tc.expItems)
+	}
+}
+
+func TestLatestBalances(t *testing.T) {
+	mkXactn := func(fileName, acct string, d time.Time, bal float64) Xactn {
+		return Xactn{Xactn: bankac.Xactn{
+			FileName:   fileName,
+			Date:       d,
+			Balance:    bal,
+			HasBalance: true,
+			Currency:   "GBP",
+			Account:    acct,
+		}}
+	}
+
+	s := &summaries{xactns: []Xactn{
+		mkXactn("jan.csv", "", mkDate(2024, time.January, 31), 100),
+		mkXactn("feb.csv", "", mkDate(2024, time.February, 28), 150),
+		mkXactn("sav1.csv", "123", mkDate(2024, time.February, 1), 1000),
+		mkXactn("sav2.csv", "123", mkDate(2024, time.February, 2), 1200),
+	}}
+
+	prog := newProg()
+	prog.reportCurrency = "GBP"
+
+	balances, lastDate := s.latestBalances(prog)
+
+	testhelper.DiffValsReport(t, "latest balances", "balances",
+		balances, map[string]acctBalance{
+			"currency: GBP": {
+				balance: 150,
+				date:    mkDate(2024, time.February, 28),
+			},
+			"account: 123": {
+				balance: 1200,
+				date:    mkDate(2024, time.February, 2),
+			},
+		})
+	testhelper.DiffValsReport(t, "latest balances", "last date",
+		lastDate, mkDate(2024, time.February, 28))
+}
+
+func TestForecastReport(t *testing.T) {
+	tree := bankac.NewTree()
+
+	s := &summaries{
+		Categoriser: bankac.NewCategoriser(bankac.NewSummaries(tree)),
+	}
+
+	addXactn := func(acct string, d time.Time, desc string, amt, bal float64) {
+		if _, ok := tree.Parent(desc); !ok {
+			if err := s.AddParent(bankac.CatAll, desc); err != nil {
+				t.Fatal("couldn't add the entry:", err)
+			}
+		}
+
+		xa := Xactn{
+			Xactn: bankac.Xactn{
+				FileName:   acct + ".csv",
+				LineNum:    len(s.xactns) + 1,
+				Date:       d,
+				Desc:       desc,
+				Balance:    bal,
+				HasBalance: true,
+				Currency:   "GBP",
+				Account:    acct,
+			},
+			SummName: desc,
+		}
+		xa.SetAmount(amt)
+
+		s.xactns = append(s.xactns, xa)
+	}
+
+	// the current account is up to date but the savings account statements
+	// stop in January so the February fee is added to the opening balance
+	addXactn("current", mkDate(2024, time.January, 1), "RENT", -500, 1500)
+	addXactn("current", mkDate(2024, time.February, 1), "RENT", -500, 1000)
+	addXactn("current", mkDate(2024, time.March, 1), "RENT", -500, 500)
+	addXactn("current", mkDate(2024, time.March, 3), "SHOP", -10, 490)
+	addXactn("savings", mkDate(2023, time.November, 15), "FEE", -5, 95)
+	addXactn("savings", mkDate(2023, time.December, 15), "FEE", -5, 90)
+	addXactn("savings", mkDate(2024, time.January, 15), "FEE", -5, 85)
+
+	testCases := []struct {
+		testhelper.ID
+		threshold float64
+		expLines  []string
+	}{
+		{
+			ID:        testhelper.MkID("low after the rent is paid"),
+			threshold: 100,
+			expLines: []string{
+				"Opening balance: 570.00 on 2024-Mar-03",
+				"This includes 1 item(s) expected since the latest" +
+					" balance of an account",
+				"2024-Mar-03 opening balance 570.00",
+				"2024-Mar-04 570.00",
+				"2024-Mar-15 FEE -5.00 565.00",
+				"2024-Mar-31 565.00",
+				"2024-Apr-01 RENT -500.00 65.00 LOW",
+				"2024-Apr-03 65.00 LOW",
+				"Lowest balance: 65.00 on 2024-Apr-01",
+			},
+		},
+		{
+			ID:        testhelper.MkID("low from the start"),
+			threshold: 1000,
+			expLines: []string{
+				"2024-Mar-03 opening balance 570.00 LOW",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		prog := newProg()
+		prog.reportCurrency = "GBP"
+		prog.forecastMonths = 1
+		prog.forecastThreshold = tc.threshold
+
+		var out strings.Builder
+
+		prog.out = &out
+
+		if err := s.forecastReport(prog); err != nil {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: unexpected error: %s", err)
+
+			continue
+		}
+
+		lines := map[string]bool{}
+		days := 0
+
+		for _, line := range strings.Split(out.String(), "\n") {
+			lines[strings.Join(strings.Fields(line), " ")] = true
+
+			if strings.HasPrefix(line, "2024-") {
+				days++
+			}
+		}
+
+		for _, expLine := range tc.expLines {
+			if !lines[expLine] {
+				t.Log(tc.IDStr())
+				t.Errorf("\t: the line %q is missing from:\n%s",
+					expLine, out.String())
+			}
+		}
+
+		testhelper.DiffInt(t, tc.IDStr(), "days", days, 32)
+	}
+}
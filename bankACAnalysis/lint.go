@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+
+	"github.com/nickwells/personal-utils/bankACAnalysis/bankac"
+)
+
+// maxUnmappedShown is the largest number of unmapped results of an edit
+// that are shown
+const maxUnmappedShown = 3
+
+// lintChecker collects the problems found in the map and edit files
+type lintChecker struct {
+	prog     *prog
+	s        *summaries
+	problems int
+}
+
+// problem reports the problem and counts it
+func (lc *lintChecker) problem(fileName string, lineNum int,
+	format string, args ...any,
+) {
+	fmt.Fprintf(lc.prog.out, "%s:%d: %s\n",
+		fileName, lineNum, fmt.Sprintf(format, args...))
+	lc.problems++
+}
+
+// replacesWhole matches the regular expression of an edit which must match
+// the whole of any description it matches so that the result of the edit
+// is just the replacement text
+var replacesWhole = regexp.MustCompile(`^(\(\?[a-zA-Z]+\))?\^.*(\.\*|\$)$`)
+
+// checkEditsStatic checks the edits without reference to any transactions.
+// It reports edits which repeat the search of an earlier edit, these can
+// only match what the earlier edit leaves, and edits which replace the
+// whole description with text that is not in the map.
+func (lc *lintChecker) checkEditsStatic() {
+	firstSearch := map[string]int{}
+
+	for i, ed := range lc.s.Edits {
+		if line, ok := firstSearch[ed.Search]; ok {
+			lc.problem(lc.prog.editFileName, ed.LineNum,
+				"the search %q repeats the one at line %d"+
+					" and may never match",
+				ed.Search, line)
+
+			continue
+		}
+
+		firstSearch[ed.Search] = ed.LineNum
+
+		if !replacesWhole.MatchString(ed.Search) {
+			continue
+		}
+
+		result := ed.Replacement
+		for _, later := range lc.s.Edits[i+1:] {
+			result = later.SearchRE.ReplaceAllLiteralString(
+				result, later.Replacement)
+		}
+
+		if !lc.s.Tree().Maps(result) {
+			lc.problem(lc.prog.editFileName, ed.LineNum,
+				"the replacement %q is not in the %s",
+				result, xactnMapDesc)
+		}
+	}
+}
+
+// checkEditsUsage checks the use made of the edits by the transactions. It
+// reports edits which never match, edits which are shadowed by earlier
+// edits and edits which give descriptions that are not in the map.
+func (lc *lintChecker) checkEditsUsage() {
+	for i, ed := range lc.s.Edits {
+		es := lc.s.editStats[i]
+
+		switch {
+		case es.matches == 0 && es.shadowedBy != 0:
+			lc.problem(lc.prog.editFileName, ed.LineNum,
+				"the search %q is shadowed by the edit at line %d",
+				ed.Search, es.shadowedBy)
+		case es.matches == 0:
+			lc.problem(lc.prog.editFileName, ed.LineNum,
+				"the search %q never matches any transaction", ed.Search)
+		}
+
+		if len(es.unmapped) > 0 {
+			shown := es.unmapped[:min(len(es.unmapped), maxUnmappedShown)]
+			more := ""
+
+			if len(es.unmapped) > len(shown) {
+				more = fmt.Sprintf(" and %d more",
+					len(es.unmapped)-len(shown))
+			}
+
+			lc.problem(lc.prog.editFileName, ed.LineNum,
+				"the edit gives descriptions which are not in the %s: %q%s",
+				xactnMapDesc, shown, more)
+		}
+	}
+}
+
+// isBuiltIn returns true if the Summary is one of the categories which are
+// created by the program rather than read from the map file
+func isBuiltIn(summ *bankac.Summary) bool {
+	return summ.Name == bankac.CatAll ||
+		(summ.Parent != nil && summ.Parent.Name == bankac.CatAll &&
+			slices.Contains(
+				[]string{
+					bankac.CatUnknown,
+					bankac.CatCash,
+					bankac.CatCheque,
+					bankac.CatTransfers,
+				},
+				summ.Name))
+}
+
+// checkMapUsage reports the map entries which receive no transactions.
+// Only the highest such entry in each part of the tree is reported.
+func (lc *lintChecker) checkMapUsage(summ *bankac.Summary) {
+	if summ.Count == 0 && !isBuiltIn(summ) {
+		if len(summ.Components) == 0 {
+			lc.problem(lc.prog.xactMapFileName, lc.s.Tree().MapLine(summ.Name),
+				"the entry %q is never used", summ.Name)
+		} else {
+			lc.problem(lc.prog.xactMapFileName, lc.s.Tree().MapLine(summ.Name),
+				"the category %q (with %d entries)"+
+					" never receives any transactions",
+				summ.Name, len(summ.Components))
+		}
+
+		return
+	}
+
+	names := slices.Sorted(maps.Keys(summ.Components))
+	for _, name := range names {
+		lc.checkMapUsage(summ.Components[name])
+	}
+}
+
+// checkConfig loads the map and edit files, and the transactions from any
+// files given, and reports any problems found. It returns an error if the
+// files cannot be read or if any problems are found.
+func (prog *prog) checkConfig() error {
+	s, err := prog.initSummaries()
+	if err != nil {
+		return err
+	}
+
+	lc := &lintChecker{prog: prog, s: s, problems: s.loadErrs}
+
+	lc.checkEditsStatic()
+
+	if len(prog.files) > 0 {
+		if err := prog.checkFiles(); err != nil {
+			return err
+		}
+
+		xas, err := prog.readAccountData()
+		if err != nil {
+			return err
+		}
+
+		s.addXactns(xas, prog.dates)
+
+		lc.checkEditsUsage()
+		lc.checkMapUsage(s.Summary(bankac.CatAll))
+	} else {
+		fmt.Fprintln(prog.out, "No account files were given so the use"+
+			" made of the edits and the map entries has not been checked")
+	}
+
+	if lc.problems == 0 {
+		fmt.Fprintln(prog.out, "No problems found")
+		return nil
+	}
+
+	return fmt.Errorf("%d problem(s) found", lc.problems)
+}
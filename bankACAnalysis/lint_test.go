@@ -0,0 +1,102 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestCheckConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	const header = "Date,Type,Sort Code,Account,Description," +
+		"Debit,Credit,Balance\n"
+
+	mapFile := writeTestFile(t, dir, "map",
+		"all food\n"+
+			"food TESCO\n"+
+			"food SAINSBURYS\n"+
+			"all travel\n"+
+			"travel TRAINLINE\n"+
+			"all gifts\n"+
+			"gifts AMAZON\n")
+	editFile := writeTestFile(t, dir, "edits",
+		"search=^TESCO.*\n"+
+			"replace=TESCO\n"+
+			"search=^TESCO.*\n"+
+			"replace=TESCO\n"+
+			"search=^SAINSBURY.*$\n"+
+			"replace=SAINSBURY\n"+
+			"search=COSTA\n"+
+			"replace=COSTA COFFEE\n"+
+			"search=EXPRESS\n"+
+			"replace=EXP\n")
+	acFile := writeTestFile(t, dir, "jan.csv", header+
+		"01/01/2024,DD,11-22-33,123,TESCO EXPRESS,10.00,,90.00\n"+
+		"02/01/2024,DD,11-22-33,123,SAINSBURYS LOCAL,5.00,,85.00\n"+
+		"03/01/2024,DD,11-22-33,123,TRAINLINE,5.00,,80.00\n")
+
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		files    []string
+		expLines []string
+	}{
+		{
+			ID:     testhelper.MkID("no account files"),
+			ExpErr: testhelper.MkExpErr("2 problem(s) found"),
+			expLines: []string{
+				editFile + `:3: the search "^TESCO.*" repeats the one` +
+					" at line 1 and may never match",
+				editFile + `:5: the replacement "SAINSBURY" is not in the ` +
+					xactnMapDesc,
+				"No account files were given so the use made of the" +
+					" edits and the map entries has not been checked",
+			},
+		},
+		{
+			ID:     testhelper.MkID("with account files"),
+			ExpErr: testhelper.MkExpErr("7 problem(s) found"),
+			files:  []string{acFile},
+			expLines: []string{
+				editFile + `:3: the search "^TESCO.*" repeats the one` +
+					" at line 1 and may never match",
+				editFile + `:5: the replacement "SAINSBURY" is not in the ` +
+					xactnMapDesc,
+				editFile + ":5: the edit gives descriptions which are" +
+					" not in the " + xactnMapDesc + `: ["SAINSBURY"]`,
+				editFile + `:7: the search "COSTA" never matches any` +
+					" transaction",
+				editFile + `:9: the search "EXPRESS" is shadowed by` +
+					" the edit at line 1",
+				mapFile + `:3: the entry "SAINSBURYS" is never used`,
+				mapFile + `:6: the category "gifts" (with 1 entries)` +
+					" never receives any transactions",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		prog := newProg()
+		prog.xactMapFileName = mapFile
+		prog.editFileName = editFile
+		prog.files = tc.files
+		prog.reportCurrency = prog.currency
+
+		var out strings.Builder
+
+		prog.out = &out
+
+		var err error
+
+		captureStderr(t, func() {
+			err = prog.checkConfig()
+		})
+
+		testhelper.CheckExpErr(t, err, tc)
+		testhelper.DiffValsReport(t, tc.IDStr(), "problems",
+			strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n"),
+			tc.expLines)
+	}
+}
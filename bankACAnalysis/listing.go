@@ -0,0 +1,127 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nickwells/col.mod/v6/col"
+	"github.com/nickwells/col.mod/v6/colfmt"
+)
+
+// The orders in which the transactions can be listed
+const (
+	listByDate   = "date"
+	listByAmount = "amount"
+)
+
+// editLines returns the lines in the edit file of the edits which changed
+// the transaction's description
+func (s *summaries) editLines(xa Xactn) string {
+	lines := []string{}
+	for _, i := range xa.EditedBy {
+		lines = append(lines, strconv.Itoa(s.Edits[i].LineNum))
+	}
+
+	return strings.Join(lines, ",")
+}
+
+// categoryXactns returns the transactions in the category, or in any of
+// its descendants, in the given order. Transactions are ordered by date or
+// else by amount, largest first; ties are broken by the file and line.
+func (s *summaries) categoryXactns(cat, order string) []Xactn {
+	xas := []Xactn{}
+
+	for _, xa := range s.xactns {
+		if s.Tree().InCategory(xa.SummName, cat) {
+			xas = append(xas, xa)
+		}
+	}
+
+	slices.SortStableFunc(xas, func(a, b Xactn) int {
+		var c int
+
+		switch order {
+		case listByAmount:
+			c = cmp.Compare(b.DebitAmt+b.CreditAmt, a.DebitAmt+a.CreditAmt)
+		default:
+			c = a.Date.Compare(b.Date)
+		}
+
+		return cmp.Or(c,
+			cmp.Compare(a.FileName, b.FileName),
+			cmp.Compare(a.LineNum, b.LineNum))
+	})
+
+	return xas
+}
+
+// listReport will list the transactions which make up the category, showing
+// for each its date, where it was found, its original and normalised
+// descriptions, the lines of the edits which changed the description, the
+// category it is in and its amounts
+func (s *summaries) listReport(prog *prog, cat string) {
+	const (
+		floatColWidth = 10
+		floatColPrec  = 2
+	)
+
+	if s.Summary(cat) == nil {
+		fmt.Fprintf(os.Stderr, "*** category: %q is not recognised\n", cat)
+		return
+	}
+
+	xas := s.categoryXactns(cat, prog.listOrder)
+
+	locWidth := len("Location")
+	origWidth := len("Original")
+	descWidth := len("Description")
+	editWidth := len("Edits")
+	catWidth := len("Category")
+
+	for _, xa := range xas {
+		locWidth = max(locWidth, len(xa.Location()))
+		origWidth = max(origWidth, len(xa.OrigDesc))
+		descWidth = max(descWidth, len(xa.Desc))
+		editWidth = max(editWidth, len(s.editLines(xa)))
+		catWidth = max(catWidth, len(s.CategoryOf(xa)))
+	}
+
+	floatCol := colfmt.Float{
+		W:    floatColWidth,
+		Prec: floatColPrec,
+		Zeroes: &colfmt.FloatZeroHandler{
+			Handle:  true,
+			Replace: "",
+		},
+	}
+
+	rpt := col.NewReportOrPanic(col.NewHeaderOrPanic(), prog.out,
+		col.New(&colfmt.Time{Format: rptDateFormat}, "Date"),
+		col.New(&colfmt.String{W: locWidth}, "Location"),
+		col.New(&colfmt.String{W: origWidth}, "Original", "Description"),
+		col.New(&colfmt.String{W: descWidth}, "Description"),
+		col.New(&colfmt.String{W: editWidth}, "Edits"),
+		col.New(&colfmt.String{W: catWidth}, "Category"),
+		col.New(&floatCol, "Debit", "Amount"),
+		col.New(&floatCol, "Credit", "Amount"),
+	)
+
+	for _, xa := range xas {
+		err := rpt.PrintRow(
+			xa.Date,
+			xa.Location(),
+			xa.OrigDesc,
+			xa.Desc,
+			s.editLines(xa),
+			s.CategoryOf(xa),
+			xa.DebitAmt,
+			xa.CreditAmt)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Couldn't print the row:", err)
+		}
+	}
+}
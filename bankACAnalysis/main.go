@@ -3,74 +3,53 @@ package main
 // bankACAnalysis
 
 import (
-	"bufio"
-	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"os"
-	"regexp"
-	"sort"
-	"strconv"
+	"slices"
 	"strings"
-	"time"
 
 	"github.com/nickwells/col.mod/v6/col"
 	"github.com/nickwells/col.mod/v6/colfmt"
+	"github.com/nickwells/personal-utils/bankACAnalysis/bankac"
+	"github.com/nickwells/verbose.mod/verbose"
 )
 
 // Created: Sun May 12 16:39:24 2019
 
-// Xactn represents a single transaction
-type Xactn struct {
-	lineNum   int
-	date      time.Time
-	xaType    string
-	desc      string
-	debitAmt  float64
-	creditAmt float64
-	balance   float64
-}
+// Xactn represents a single transaction, as read from the file, together
+// with the details recorded as it is categorised
+type Xactn = bankac.Categorised
 
-// Summary represents a summary of the account transactions
-type Summary struct {
-	name       string
-	count      int
-	firstDate  time.Time
-	lastDate   time.Time
-	debitAmt   float64
-	creditAmt  float64
-	parent     *Summary
-	depth      int
-	components map[string]*Summary
-}
+const xactnMapDesc = "map of transaction types"
 
+// The descriptions of the categorisation files
 const (
-	catAll     = "all"
-	catUnknown = "unknown"
-	catCash    = "cash"
-	catCheque  = "cheque"
-
-	editTypeSearch  = "search"
-	editTypeReplace = "replace"
+	rulesDesc         = "categorisation rules"
+	splitDesc         = "split"
+	splitOverrideDesc = "split override"
+	tagDesc           = "tag"
 )
 
-const xactnMapDesc = "map of transaction types"
-
 const tabWidth = 4
 
-// Edit represents a substitution to be made to a transaction description
-type Edit struct {
-	search      string
-	searchRE    *regexp.Regexp
-	replacement string
-}
-
+// summaries holds the Summary records for the tree of categories together
+// with the transactions and the details used to categorise them
 type summaries struct {
-	parentOf     map[string]string
-	summaries    map[string]*Summary
-	edits        []Edit
-	maxDepth     int
-	maxNameWidth int
+	*bankac.Categoriser
+
+	budgets map[string][]budgetEntry
+	xactns  []Xactn
+
+	// the number of errors found while reading the configuration files and
+	// the use made of each edit
+	loadErrs  int
+	editStats []editStat
+
+	// compared holds the summaries for the period or files being compared
+	// against
+	compared *summaries
 }
 
 type reportStyle int
@@ -80,237 +59,173 @@ const (
 	summaryReport
 )
 
-// openFileOrDie will try to open the given file and will return the open
-// file if successful and will print an error message and exit of not.
-func openFileOrDie(fileName, desc string) *os.File {
+// openFile will try to open the given file and will return the open file
+// if successful and an error describing the file if not
+func openFile(fileName, desc string) (*os.File, error) {
 	f, err := os.Open(fileName) //nolint:gosec
 	if err != nil {
-		fmt.Printf("Couldn't open the %s file: %s", desc, err)
-		os.Exit(1)
+		return nil, fmt.Errorf("couldn't open the %s file: %w", desc, err)
 	}
 
-	return f
+	return f, nil
 }
 
 // populateParents constructs the parent tree of transactions from the
-// transaction map file
-func (s *summaries) populateParents(prog *prog) {
-	s.parentOf[catAll] = catAll
+// transaction map file and then creates the Summary records for the
+// entries in the tree
+func (s *summaries) populateParents(prog *prog) error {
+	tree := bankac.NewTree()
 
-	err := s.addParent(catAll, catUnknown)
-	if err != nil {
-		fmt.Printf("Cannot initialise the %s: %s\n", xactnMapDesc, err)
-		os.Exit(1)
-	}
-
-	err = s.addParent(catAll, catCash)
-	if err != nil {
-		fmt.Printf("Cannot initialise the %s: %s\n", xactnMapDesc, err)
-		os.Exit(1)
+	if len(prog.ownAccounts) > 0 && prog.transfers == transfersCategorise {
+		err := tree.AddParent(bankac.CatAll, bankac.CatTransfers)
+		if err != nil {
+			return fmt.Errorf("cannot initialise the %s: %w",
+				xactnMapDesc, err)
+		}
 	}
 
-	err = s.addParent(catAll, catCheque)
+	mf, err := openFile(prog.xactMapFileName, xactnMapDesc)
 	if err != nil {
-		fmt.Printf("Cannot initialise the %s: %s\n", xactnMapDesc, err)
-		os.Exit(1)
+		return err
 	}
-
-	mf := openFileOrDie(prog.xactMapFileName, xactnMapDesc)
 	defer mf.Close()
 
-	mScanner := bufio.NewScanner(mf)
-	lineNum := 0
-
-	for mScanner.Scan() {
-		lineNum++
+	if prog.mapFormat == mapFmtNested {
+		s.reportLoadErrs(tree.ReadNestedMap(prog.xactMapFileName, mf))
+	} else {
+		s.reportLoadErrs(tree.ReadFlatMap(prog.xactMapFileName, mf))
+	}
 
-		line := mScanner.Text()
-		if line == "" {
-			continue
-		}
+	s.Categoriser = bankac.NewCategoriser(bankac.NewSummaries(tree))
+	s.Edited = s.recordEdits
+	s.Note = func(msg string) { verbose.Println(msg) }
+	s.Problem = func(err error) { fmt.Fprintln(os.Stderr, err) }
 
-		from, to, ok := strings.Cut(line, " ")
-		if !ok {
-			fmt.Printf("%s:%d: Bad entry in the %s: there is no 'to' part\n",
-				prog.xactMapFileName, lineNum, xactnMapDesc)
-		}
+	return nil
+}
 
-		err = s.addParent(from, to)
-		if err != nil {
-			fmt.Printf("%s:%d: Bad entry in the %s: %s\n",
-				prog.xactMapFileName, lineNum, xactnMapDesc, err)
-		}
+// reportLoadErrs reports the errors found while reading the configuration
+// files and adds them to the count of such errors
+func (s *summaries) reportLoadErrs(errs []error) {
+	for _, err := range errs {
+		fmt.Fprintln(os.Stderr, err)
 	}
+
+	s.loadErrs += len(errs)
 }
 
 // populateEdits constructs the slice of editing rules to be performed on
 // transaction descriptions
-func (s *summaries) populateEdits(prog *prog) {
-	ef := openFileOrDie(prog.editFileName, "transaction edits")
+func (s *summaries) populateEdits(prog *prog) error {
+	ef, err := openFile(prog.editFileName, "transaction edits")
+	if err != nil {
+		return err
+	}
 	defer ef.Close()
 
-	eScanner := bufio.NewScanner(ef)
-	lineNum := 0
-	prevType := ""
-
-	var searchRE *regexp.Regexp
-
-	var searchStr string
-
-	var errFound bool
+	var errs []error
 
-	var err error
+	s.Edits, errs = bankac.ReadEdits(prog.editFileName, ef)
+	s.reportLoadErrs(errs)
+	s.editStats = make([]editStat, len(s.Edits))
 
-	const errIntro = "Bad transaction edits entry"
+	return nil
+}
 
-	for eScanner.Scan() {
-		lineNum++
+// populateRules reads the categorisation rules from the rules file, if it
+// is given
+func (s *summaries) populateRules(prog *prog) error {
+	if prog.rulesFileName == "" {
+		return nil
+	}
 
-		line := eScanner.Text()
-		if line == "" {
-			continue
-		}
+	rf, err := openFile(prog.rulesFileName, rulesDesc)
+	if err != nil {
+		return err
+	}
+	defer rf.Close()
 
-		entryType, entryValue, ok := strings.Cut(line, "=")
-		if !ok {
-			fmt.Printf("%s:%d: Bad entry in the %s: missing '=': %s\n",
-				prog.editFileName, lineNum, errIntro, line)
+	s.reportLoadErrs(s.ReadRules(prog.rulesFileName, rf))
 
-			errFound = true
+	return nil
+}
 
-			continue
+// populateSplits reads the split file and the split override file, if
+// they are given
+func (s *summaries) populateSplits(prog *prog) error {
+	if prog.splitFileName != "" {
+		sf, err := openFile(prog.splitFileName, splitDesc)
+		if err != nil {
+			return err
 		}
+		defer sf.Close()
 
-		switch entryType {
-		case editTypeSearch:
-			if prevType == editTypeSearch {
-				fmt.Printf(
-					"%s:%d: %s: %q entry missing for previous search\n",
-					prog.editFileName, lineNum, errIntro, editTypeReplace)
-			}
-
-			errFound = false
-			searchStr = entryValue
-
-			searchRE, err = regexp.Compile(searchStr)
-			if err != nil {
-				fmt.Printf("%s:%d: %s: Couldn't compile the regexp: %s\n",
-					prog.editFileName, lineNum, errIntro, err)
-
-				errFound = true
-			}
-		case editTypeReplace:
-			if !errFound {
-				s.edits = append(s.edits, Edit{
-					search:      searchStr,
-					searchRE:    searchRE,
-					replacement: entryValue,
-				})
-			}
-		default:
-			fmt.Printf("%s:%d: %s: Bad type: %s\n",
-				prog.editFileName, lineNum, errIntro, entryType)
+		s.reportLoadErrs(s.ReadSplits(prog.splitFileName, sf))
+	}
 
-			errFound = true
+	if prog.splitOverrideFileName != "" {
+		of, err := openFile(prog.splitOverrideFileName, splitOverrideDesc)
+		if err != nil {
+			return err
 		}
+		defer of.Close()
 
-		prevType = entryType
+		s.reportLoadErrs(
+			s.ReadSplitOverrides(prog.splitOverrideFileName, of))
 	}
+
+	return nil
 }
 
-// initSummaries returns an initialised Summaries structure
-func (prog *prog) initSummaries() *summaries {
-	s := summaries{
-		parentOf:  make(map[string]string),
-		summaries: make(map[string]*Summary),
+// populateTags reads the tag file, if it is given
+func (s *summaries) populateTags(prog *prog) error {
+	if prog.tagFileName == "" {
+		return nil
 	}
 
-	s.summaries[catAll] = &Summary{
-		name:       catAll,
-		components: make(map[string]*Summary),
+	tf, err := openFile(prog.tagFileName, tagDesc)
+	if err != nil {
+		return err
 	}
-	s.populateParents(prog)
+	defer tf.Close()
 
-	s.populateEdits(prog)
+	s.reportLoadErrs(s.ReadTags(prog.tagFileName, tf))
 
-	return &s
+	return nil
 }
 
-// addParent adds the parent/child relationship so that a given summary can
-// find its parent. It is an error if the parent does not already exist.
-func (s *summaries) addParent(parent, child string) error {
-	if _, ok := s.parentOf[parent]; !ok {
-		return fmt.Errorf("%q (parent of %q) doesn't exist",
-			parent, child)
-	}
+// initSummaries returns an initialised Summaries structure, populated from
+// the map file and the other configuration files. It returns an error if
+// any of the files cannot be read.
+func (prog *prog) initSummaries() (*summaries, error) {
+	s := &summaries{}
 
-	if oldParent, ok := s.parentOf[child]; ok {
-		if oldParent != parent {
-			return fmt.Errorf("%q already has a parent: %q != %q",
-				child, parent, oldParent)
-		}
-
-		return nil
+	err := s.populateParents(prog)
+	if err == nil {
+		err = s.populateEdits(prog)
 	}
 
-	pSum, ok := s.summaries[parent]
-	if !ok {
-		return fmt.Errorf("%q (parent of %q) has no summary record"+
-			" - check the transaction map file",
-			parent, child)
+	if err == nil {
+		err = s.populateRules(prog)
 	}
 
-	cSum := &Summary{
-		name:       child,
-		parent:     pSum,
-		depth:      pSum.depth + 1,
-		components: make(map[string]*Summary),
+	if err == nil {
+		err = s.populateSplits(prog)
 	}
-	s.summaries[child] = cSum
-
-	s.maxDepth = max(cSum.depth, s.maxDepth)
 
-	s.maxNameWidth = max(len(cSum.name), s.maxNameWidth)
-
-	pSum.components[child] = cSum
-	s.parentOf[child] = parent
-
-	return nil
-}
-
-// summarise will summarise the transaction working its way up to the top of
-// the tree of Summary records
-func (s *summaries) summarise(xa Xactn) {
-	summ, ok := s.summaries[xa.desc]
-	if !ok {
-		fmt.Println("Couldn't find the summary record for :", xa)
-		return
+	if err == nil {
+		err = s.populateTags(prog)
 	}
 
-	summ.add(xa)
-}
-
-// add will add the values to the summary record and move on to the parent
-// (if there is one)
-func (s *Summary) add(xa Xactn) {
-	if s.count == 0 {
-		s.firstDate = xa.date
-		s.lastDate = xa.date
-	} else {
-		if xa.date.After(s.lastDate) {
-			s.lastDate = xa.date
-		} else if s.firstDate.After(xa.date) {
-			s.firstDate = xa.date
-		}
+	if err == nil {
+		err = s.populateBudgets(prog)
 	}
 
-	s.count++
-	s.debitAmt += xa.debitAmt
-	s.creditAmt += xa.creditAmt
-
-	if s.parent != nil {
-		s.parent.add(xa)
+	if err != nil {
+		return nil, err
 	}
+
+	return s, nil
 }
 
 // prog holds the parameters and current status of the program
@@ -329,23 +244,163 @@ type prog struct {
 	// transaction names
 	editFileName string
 
+	// the name of the file containing the rules used to categorise
+	// transactions
+	rulesFileName string
+
+	// the names of the files giving the splits of transactions across
+	// categories by description and for individual transactions
+	splitFileName         string
+	splitOverrideFileName string
+
+	// the name of the file giving the tags of descriptions and of
+	// individual transactions
+	tagFileName string
+
+	// the name of the file containing the budget for each category
+	budgetFileName string
+
 	// don't suppress printing of summary records for which there are no
 	// transactions
 	showZeros bool
 
-	// Skip the first (header) line in the file of transactions.
-	skipFirstLine bool
+	// treat transactions repeated in more than one file as an error
+	overlapIsError bool
+
+	// check the running balances and the gaps between files
+	checkBalances bool
+	maxGapDays    int
 
 	style         reportStyle
 	minimalAmount float64
 	showCats      []string
+
+	// the CSV layouts, the definitions are parsed after the parameters
+	// have been read to give the layouts, one of which is chosen by name
+	// and given to the reader
+	layoutDefs []string
+	layouts    map[string]bankac.CSVLayout
+	layoutName string
+
+	// the format of the bank account files and the reader of the files,
+	// this holds the CSV layout, whether to skip the first (header) line
+	// of CSV files and the layout of the dates in QIF files
+	fileFormat string
+	reader     *bankac.Reader
+
+	// the period over which to break down the report and the value to
+	// show for each period
+	periodBy    string
+	periodValue string
+
+	// our own accounts and how to treat the transfers between them
+	ownAccountDefs []string
+	ownAccounts    bankac.OwnAccounts
+	transfers      string
+	transferDays   int
+
+	// report the recurring payments and the fewest payments that are
+	// taken as recurring
+	recurring         bool
+	recurringMinCount int
+
+	// list the transactions in each category rather than summarise them
+	listXactns bool
+	listOrder  string
+
+	// report the use made of the edits
+	editReport bool
+
+	// report the totals for each tag
+	tagReport bool
+
+	// forecast the balance: the number of months to forecast, the balance
+	// below which it is flagged and the file of planned items
+	forecast          bool
+	forecastMonths    int
+	forecastThreshold float64
+	plannedFileName   string
+
+	// report the transactions which look out of character: the factor
+	// applied to the spread of the amounts in a category to find its usual
+	// range and the smallest first payment to a payee which is reported
+	anomalyReport bool
+	anomalyFactor float64
+	newPayeeMin   float64
+
+	// draw charts of the categories: the depth of the entries charted, how
+	// many are shown and the width of the chart, zero if it is to be found
+	// from the terminal
+	chart      bool
+	chartDepth int
+	chartTop   int
+	chartCols  int
+
+	// the dates and files to compare against
+	compareDates       dateRange
+	compareNamedPeriod string
+	compareFiles       []string
+
+	// only check the map and edit files
+	checkCfg bool
+
+	// the format of the map file and whether to convert it to the nested
+	// format rather than report the transactions
+	mapFormat      string
+	convertMapFlag bool
+
+	// the store of transactions and whether to import the transactions
+	// into it rather than report
+	storeFileName string
+	importToStore bool
+
+	// interactively classify the unknown transactions rather than report
+	classify bool
+
+	// the currencies of the accounts and of the report and the exchange
+	// rates used to convert between them
+	currency       string
+	fileCurrencies []string
+	reportCurrency string
+	ratesFileName  string
+	rates          exchangeRates
+	showOrigCcy    bool
+
+	// where the report is written and in what format
+	out            io.Writer
+	outputFileName string
+	outputFormat   string
+
+	// the range of dates of the transactions to be reported
+	dates       dateRange
+	namedPeriod string
 }
 
 func newProg() *prog {
 	return &prog{
-		skipFirstLine: true,
-		style:         showLeafEntries,
-		showCats:      []string{catAll},
+		style:        showLeafEntries,
+		showCats:     []string{bankac.CatAll},
+		layoutName:   bankac.DfltLayoutName,
+		fileFormat:   fmtAuto,
+		reader:       newReader(),
+		maxGapDays:   dfltMaxGapDays,
+		periodBy:     periodNone,
+		periodValue:  valNet,
+		currency:     dfltCurrency,
+		mapFormat:    mapFmtFlat,
+		listOrder:    listByDate,
+		transfers:    transfersCategorise,
+		transferDays: dfltTransferDays,
+
+		chartDepth:     dfltChartDepth,
+		chartTop:       dfltChartTop,
+		forecastMonths: dfltForecastMonths,
+		anomalyFactor:  dfltAnomalyFactor,
+		newPayeeMin:    dfltNewPayeeMin,
+
+		recurringMinCount: dfltRecurringMinCount,
+		out:               os.Stdout,
+		outputFormat:      outText,
 	}
 }
 
@@ -360,137 +415,240 @@ func main() {
 		prog.files = append(prog.files, prog.acFileName)
 	}
 
-	summaries := prog.getAccountData()
+	if err := prog.run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
 
-	sep := ""
-	for _, cat := range prog.showCats {
-		fmt.Print(sep)
-		sep = "\n"
+// run carries out the action chosen by the parameters: checking the
+// configuration, converting the map, importing the transactions into the
+// store, classifying the unknown transactions or writing the report
+func (prog *prog) run() error {
+	if prog.checkCfg {
+		return prog.checkConfig()
+	}
+
+	if prog.convertMapFlag {
+		if err := prog.withOutput(prog.convertMap); err != nil {
+			return fmt.Errorf("couldn't convert the map: %w", err)
+		}
 
-		summaries.report(prog, cat)
+		return nil
 	}
-}
 
-// getAccountData opens each file in turn and reads from it to populate the
-// summaries
-func (prog *prog) getAccountData() *summaries {
-	prog.checkFiles()
+	if prog.importToStore {
+		err := prog.checkFiles()
+		if err == nil {
+			err = prog.importXactns()
+		}
 
-	s := prog.initSummaries()
+		if err != nil {
+			return fmt.Errorf("couldn't import the transactions: %w", err)
+		}
 
-	for _, name := range prog.files {
-		f := openFileOrDie(name, "bank account")
-		r := csv.NewReader(f)
-		s.populateSummaries(prog, name, r)
-		f.Close()
+		return nil
+	}
+
+	summaries, err := prog.getAccountData()
+	if err != nil {
+		return err
+	}
+
+	if prog.classify {
+		if err := prog.classifyUnknowns(summaries); err != nil {
+			return fmt.Errorf("couldn't classify the unknown transactions: %w",
+				err)
+		}
+
+		return nil
+	}
+
+	err = prog.withOutput(func() error {
+		return prog.writeReport(summaries)
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't write the report: %w", err)
 	}
 
-	return s
+	return nil
 }
 
-// checkFiles checks the slice of files and if a duplicate is found it will
-// report an error and exit
-func (prog *prog) checkFiles() {
-	if len(prog.files) == 0 {
-		fmt.Println("Some account files must be given")
-		os.Exit(1)
+// withOutput calls the function to write to the output. If an output file
+// has been given it is created before the function is called and closed
+// afterwards.
+func (prog *prog) withOutput(f func() error) error {
+	if prog.outputFileName == "" {
+		return f()
 	}
 
-	m := map[string]bool{}
+	of, err := os.Create(prog.outputFileName)
+	if err != nil {
+		return fmt.Errorf("couldn't create the output file: %w", err)
+	}
 
-	var dupFound int
+	prog.out = of
 
-	for _, f := range prog.files {
-		if m[f] {
-			fmt.Println("File name", f,
-				"appears more than once in the list of files")
+	err = f()
 
-			dupFound++
-		}
+	if cErr := of.Close(); cErr != nil && err == nil {
+		err = fmt.Errorf("couldn't close the output file: %w", cErr)
+	}
 
-		m[f] = true
+	return err
+}
+
+// getAccountData checks the files and initialises the summaries and then
+// populates them from the files. If a comparison is to be made the
+// summaries to compare against are populated as well.
+func (prog *prog) getAccountData() (*summaries, error) {
+	if err := prog.checkFiles(); err != nil {
+		return nil, err
 	}
 
-	if dupFound > 0 {
-		os.Exit(1)
+	s, err := prog.initSummaries()
+	if err != nil {
+		return nil, err
 	}
-}
 
-// populateSummaries fills in the summaries from the lines read from the
-// io.Reader
-func (s *summaries) populateSummaries(prog *prog, name string, r *csv.Reader) {
-	lineNum := 0
+	xas, err := prog.readAccountData()
+	if err != nil {
+		return nil, err
+	}
 
-	for {
-		parts, err := r.Read()
-		if err == io.EOF {
-			break
-		}
+	s.addXactns(xas, prog.dates)
 
+	if prog.comparing() {
+		s.compared, err = prog.compareData(xas)
 		if err != nil {
-			fmt.Println("Error found while reading:", name)
-			fmt.Println(err)
-			os.Exit(1)
+			return nil, err
 		}
+	}
 
-		lineNum++
-		if prog.skipFirstLine && lineNum == 1 {
-			continue // ignore the first line of headings
-		}
+	return s, nil
+}
+
+// readAccountData reads the transactions from the files and, if a store is
+// given, adds them to those already in the store. Transfers between the
+// user's own accounts are then matched and the transactions are returned
+func (prog *prog) readAccountData() ([]Xactn, error) {
+	xas, err := prog.readFiles()
+	if err != nil {
+		return nil, err
+	}
 
-		xa, err := s.mkXactn(lineNum, parts)
+	if prog.storeFileName != "" {
+		xas, err = prog.withStore(xas)
 		if err != nil {
-			fmt.Println(err)
-			continue
+			return nil, err
 		}
-
-		s.createNewMapEntries(name, lineNum, xa)
-		s.summarise(xa)
 	}
+
+	return prog.matchTransfers(xas), nil
 }
 
-// createNewMapEntries will create new parent/child map entries for the
-// transaction if it is not already known or if it is a cheque or cashpoint
-// withdrawal
-func (s *summaries) createNewMapEntries(fileName string, lineNum int, xa Xactn) {
-	switch xa.xaType {
-	case "CHQ":
-		err := s.addParent(catCheque, xa.desc)
-		if err != nil {
-			fmt.Printf(
-				"%s:%d: Can't add the cheque to the %s: %s\n",
-				fileName, lineNum, xactnMapDesc, err)
-		}
-	case "CPT":
-		err := s.addParent(catCash, xa.desc)
+// readFiles opens each file in turn and reads the transactions from it. Any
+// transactions which are repeated in later files are removed and the
+// remainder are returned
+func (prog *prog) readFiles() ([]Xactn, error) {
+	byFile := map[string][]Xactn{}
+
+	for _, name := range prog.files {
+		xas, err := prog.readXactns(name)
 		if err != nil {
-			fmt.Printf(
-				"%s:%d: Can't add the cashpoint withdrawal to the %s: %s\n",
-				fileName, lineNum, xactnMapDesc, err)
+			return nil, fmt.Errorf("error found while reading %s: %w",
+				name, err)
 		}
-	default:
-		if _, ok := s.parentOf[xa.desc]; ok {
-			return
+
+		byFile[name] = xas
+	}
+
+	if prog.checkBalances {
+		prog.reconcile(byFile)
+	}
+
+	if err := prog.readRates(); err != nil {
+		return nil, err
+	}
+
+	for _, name := range prog.files {
+		byFile[name] = prog.setCurrencies(name, byFile[name])
+	}
+
+	xas, pairs, overlaps := removeDuplicates(prog.files, byFile)
+	reportOverlaps(pairs, overlaps)
+
+	if prog.overlapIsError && len(pairs) > 0 {
+		return nil, errors.New("overlapping files are not allowed")
+	}
+
+	return xas, nil
+}
+
+// addXactns adds those transactions which are in the date range to the
+// summaries
+func (s *summaries) addXactns(xas []Xactn, dates dateRange) {
+	for _, xa := range xas {
+		if dates.contains(xa.Date) {
+			s.addXactn(xa)
 		}
+	}
+}
 
-		err := s.addParent(catUnknown, xa.desc)
-		if err != nil {
-			fmt.Printf(
-				"%s:%d: Can't add the unknown entry to the %s: %s\n",
-				fileName, lineNum, xactnMapDesc, err)
+// checkFiles checks the slice of files and returns an error if none are
+// given or if any file is given more than once
+func (prog *prog) checkFiles() error {
+	if len(prog.files) == 0 &&
+		(prog.storeFileName == "" || prog.importToStore) {
+		return errors.New("some account files must be given")
+	}
+
+	m := map[string]bool{}
+	errs := []error{}
+
+	for _, f := range prog.files {
+		if m[f] {
+			errs = append(errs, fmt.Errorf("file name %s appears more"+
+				" than once in the list of files", f))
 		}
+
+		m[f] = true
 	}
+
+	return errors.Join(errs...)
+}
+
+// addXactn categorises the transaction and adds it to the summaries. If
+// it is split each of its parts is added.
+func (s *summaries) addXactn(xa Xactn) {
+	s.xactns = append(s.xactns, s.Categorise(xa)...)
 }
 
-// normalise converts the string into a 'normal' form - this involves editing
-// it to replace multiple alternative spellings into a single variant. It
-// returns after all the edits have been applied
-func (s *summaries) normalise(str string) string {
-	for _, ed := range s.edits {
-		str = ed.searchRE.ReplaceAllLiteralString(str, ed.replacement)
+// recordEdits records the use made of each edit in normalising the
+// description of the transaction
+func (s *summaries) recordEdits(xa Xactn, n bankac.Normalised) {
+	lastChange := -1
+
+	for i, ed := range s.Edits {
+		if slices.Contains(n.Matched, i) {
+			s.editStats[i].matches++
+
+			if slices.Contains(n.Changed, i) {
+				s.editStats[i].add(xa)
+				lastChange = i
+			}
+
+			continue
+		}
+
+		if lastChange >= 0 && ed.SearchRE.MatchString(xa.OrigDesc) {
+			s.editStats[i].shadowedBy = s.Edits[lastChange].LineNum
+		}
 	}
 
-	return str
+	if lastChange >= 0 && !s.Tree().Maps(n.Desc) {
+		s.editStats[lastChange].addUnmapped(n.Desc)
+	}
 }
 
 // report will report the summaries
@@ -502,9 +660,9 @@ func (s *summaries) report(prog *prog, cat string) {
 		countColWidth = 5
 	)
 
-	summ, ok := s.summaries[cat]
-	if !ok {
-		fmt.Printf("*** category: %q is not recognised\n", cat)
+	summ := s.Summary(cat)
+	if summ == nil {
+		fmt.Fprintf(os.Stderr, "*** category: %q is not recognised\n", cat)
 		return
 	}
 
@@ -524,9 +682,7 @@ func (s *summaries) report(prog *prog, cat string) {
 		},
 	}
 
-	rpt := col.StdRpt(
-		col.New(&colfmt.String{W: tabWidth*s.maxDepth + s.maxNameWidth},
-			"Transaction Type"),
+	cols := []*col.Col{
 		col.New(&colfmt.Int{W: countColWidth}, "Count"),
 		col.New(&colfmt.Time{Format: "2006-Jan-02"},
 			"Date of", "First", "Transaction"),
@@ -537,9 +693,19 @@ func (s *summaries) report(prog *prog, cat string) {
 		col.New(&floatCol, "Credit", "Amount"),
 		col.New(&pctCol, "%age"),
 		col.New(&floatCol, "Nett", "Amount"),
-	)
+	}
 
-	summ.report(prog, rpt, summ.debitAmt, summ.creditAmt, 0)
+	if prog.showOrigCcy {
+		cols = append(cols,
+			col.New(&colfmt.String{}, "Nett Amount", "by Currency"))
+	}
+
+	rpt := col.NewReportOrPanic(col.NewHeaderOrPanic(), prog.out,
+		col.New(&colfmt.String{W: tabWidth*s.MaxDepth() + s.MaxNameWidth()},
+			"Transaction Type"),
+		cols...)
+
+	prog.summaryRows(summ, rpt, summ.DebitAmt, summ.CreditAmt, 0)
 }
 
 // calcPct calculates the amount as a proportion of the total, if the total
@@ -552,99 +718,49 @@ func calcPct(amt, tot float64) float64 {
 	return amt / tot
 }
 
-func (s *Summary) report(
-	prog *prog,
+// summaryRows prints the row for the Summary and then for its components
+func (prog *prog) summaryRows(
+	s *bankac.Summary,
 	rpt *col.Report,
 	totDebit, totCredit float64,
 	indent int,
 ) {
-	if prog.style == summaryReport && len(s.components) == 0 {
+	if prog.isHidden(s) {
 		return
 	}
 
-	if !prog.showZeros && s.count == 0 {
-		return
+	vals := []any{
+		strings.Repeat(" ", tabWidth*indent) + s.Name,
+		s.Count,
+		s.FirstDate, s.LastDate,
+		s.DebitAmt, calcPct(s.DebitAmt, totDebit),
+		s.CreditAmt, calcPct(s.CreditAmt, totCredit),
+		s.CreditAmt - s.DebitAmt,
 	}
 
-	if s.creditAmt+s.debitAmt < prog.minimalAmount {
-		return
+	if prog.showOrigCcy {
+		vals = append(vals, currencyTotals(s))
 	}
 
-	err := rpt.PrintRow(
-		strings.Repeat(" ", tabWidth*indent)+s.name,
-		s.count,
-		s.firstDate, s.lastDate,
-		s.debitAmt, calcPct(s.debitAmt, totDebit),
-		s.creditAmt, calcPct(s.creditAmt, totCredit),
-		s.creditAmt-s.debitAmt)
+	err := rpt.PrintRow(vals...)
 	if err != nil {
-		fmt.Println("Couldn't print the row:", err)
-	}
-
-	compList := []*Summary{}
-	for _, c := range s.components {
-		compList = append(compList, c)
-	}
-
-	sort.Slice(compList, func(i, j int) bool {
-		return (compList[i].debitAmt + compList[i].creditAmt) >
-			(compList[j].debitAmt + compList[j].creditAmt)
-	})
-
-	for _, c := range compList {
-		c.report(prog, rpt, totDebit, totCredit, indent+1)
-	}
-}
-
-// parseNum returns 0.0 if the string is empty, otherwise it will parse the
-// number as a float
-func parseNum(s, name string) (float64, error) {
-	if s == "" {
-		return 0.0, nil
+		fmt.Fprintln(os.Stderr, "Couldn't print the row:", err)
 	}
 
-	n, err := strconv.ParseFloat(s, 64)
-	if err != nil {
-		return 0.0, fmt.Errorf("couldn't parse the %s: %s", name, err)
+	for _, c := range s.SortedComponents() {
+		prog.summaryRows(c, rpt, totDebit, totCredit, indent+1)
 	}
-
-	return n, nil
 }
 
-// mkXactn converts the slice of strings into an transaction record
-func (s *summaries) mkXactn(lineNum int, parts []string) (Xactn, error) {
-	date, err := time.Parse("02/01/2006", parts[0])
-	if err != nil {
-		return Xactn{}, fmt.Errorf("couldn't parse the date: %s", err)
-	}
-
-	da, err := parseNum(parts[5], "debit amount")
-	if err != nil {
-		return Xactn{}, err
-	}
-
-	ca, err := parseNum(parts[6], "debit amount")
-	if err != nil {
-		return Xactn{}, err
-	}
-
-	bal, err := parseNum(parts[7], "balance amount")
-	if err != nil {
-		return Xactn{}, err
+// isHidden returns true if the Summary should not be shown in the report
+func (prog *prog) isHidden(s *bankac.Summary) bool {
+	if prog.style == summaryReport && len(s.Components) == 0 {
+		return true
 	}
 
-	desc := parts[4]
-	if _, ok := s.parentOf[desc]; !ok {
-		desc = s.normalise(desc)
+	if !prog.showZeros && s.Count == 0 {
+		return true
 	}
 
-	return Xactn{
-		lineNum:   lineNum,
-		date:      date,
-		xaType:    parts[1],
-		desc:      desc,
-		debitAmt:  da,
-		creditAmt: ca,
-		balance:   bal,
-	}, nil
+	return s.CreditAmt+s.DebitAmt < prog.minimalAmount
 }
@@ -0,0 +1,17 @@
+package main
+
+// The formats of the map file
+const (
+	mapFmtFlat   = "flat"
+	mapFmtNested = "nested"
+)
+
+// convertMap writes the map in the nested form to the output
+func (prog *prog) convertMap() error {
+	s, err := prog.initSummaries()
+	if err != nil {
+		return err
+	}
+
+	return s.Tree().WriteNestedMap(prog.out)
+}
@@ -0,0 +1,394 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/nickwells/personal-utils/bankACAnalysis/bankac"
+)
+
+// The formats in which the report can be written
+const (
+	outText     = "text"
+	outCSV      = "csv"
+	outJSON     = "json"
+	outMarkdown = "markdown"
+)
+
+// jsonSummary is the form in which a Summary is written as JSON
+type jsonSummary struct {
+	Name      string             `json:"name"`
+	Count     int                `json:"count"`
+	FirstDate string             `json:"firstDate,omitempty"`
+	LastDate  string             `json:"lastDate,omitempty"`
+	Debit     float64            `json:"debit"`
+	Credit    float64            `json:"credit"`
+	Net       float64            `json:"net"`
+	Original  map[string]float64 `json:"original,omitempty"`
+	Children  []*jsonSummary     `json:"children,omitempty"`
+}
+
+// roundAmt rounds the amount to the nearest penny
+func roundAmt(amt float64) float64 {
+	const pennies = 100
+
+	return math.Round(amt*pennies) / pennies
+}
+
+// fmtDate returns the date of the first or last transaction in the
+// Summary formatted for the machine-readable reports. It returns the empty
+// string if there are no transactions.
+func fmtDate(s *bankac.Summary, first bool) string {
+	if s.Count == 0 {
+		return ""
+	}
+
+	if first {
+		return s.FirstDate.Format(paramDateFormat)
+	}
+
+	return s.LastDate.Format(paramDateFormat)
+}
+
+// toJSON converts the Summary and its visible components into the form to
+// be written as JSON. It returns nil if the Summary is not to be shown.
+func (prog *prog) toJSON(s *bankac.Summary) *jsonSummary {
+	if prog.isHidden(s) {
+		return nil
+	}
+
+	js := &jsonSummary{
+		Name:      s.Name,
+		Count:     s.Count,
+		FirstDate: fmtDate(s, true),
+		LastDate:  fmtDate(s, false),
+		Debit:     roundAmt(s.DebitAmt),
+		Credit:    roundAmt(s.CreditAmt),
+		Net:       roundAmt(s.CreditAmt - s.DebitAmt),
+	}
+
+	if prog.showOrigCcy {
+		js.Original = map[string]float64{}
+		for ccy, a := range s.ByCurrency {
+			js.Original[ccy] = roundAmt(a.CreditAmt - a.DebitAmt)
+		}
+	}
+
+	for _, c := range s.SortedComponents() {
+		if cjs := prog.toJSON(c); cjs != nil {
+			js.Children = append(js.Children, cjs)
+		}
+	}
+
+	return js
+}
+
+// walk calls the function for the Summary and each of its visible
+// components, in report order, passing the depth below the starting
+// Summary
+func (prog *prog) walk(
+	s *bankac.Summary, depth int, f func(*bankac.Summary, int) error,
+) error {
+	if prog.isHidden(s) {
+		return nil
+	}
+
+	if err := f(s, depth); err != nil {
+		return err
+	}
+
+	for _, c := range s.SortedComponents() {
+		if err := prog.walk(c, depth+1, f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fmtAmt formats the amount for the machine-readable reports
+func fmtAmt(amt float64) string {
+	return strconv.FormatFloat(roundAmt(amt), 'f', 2, 64)
+}
+
+// reportCSV writes the summaries for the categories as comma-separated
+// values with a header line. If the amounts in the original currencies are
+// to be shown there is a column for the nett amount in each currency.
+func (s *summaries) reportCSV(prog *prog, w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	hdr := []string{
+		"category", "parent", "depth", "count",
+		"first date", "last date",
+		"debit", "credit", "net",
+	}
+
+	var ccys []string
+	if prog.showOrigCcy {
+		ccys = s.Summary(bankac.CatAll).Currencies()
+		for _, ccy := range ccys {
+			hdr = append(hdr, "net "+ccy)
+		}
+	}
+
+	if err := cw.Write(hdr); err != nil {
+		return err
+	}
+
+	for _, cat := range prog.showCats {
+		summ := s.Summary(cat)
+		if summ == nil {
+			return fmt.Errorf("category: %q is not recognised", cat)
+		}
+
+		err := prog.walk(summ, 0, func(summ *bankac.Summary, depth int) error {
+			rec := []string{
+				summ.Name, summ.ParentName(),
+				strconv.Itoa(depth), strconv.Itoa(summ.Count),
+				fmtDate(summ, true), fmtDate(summ, false),
+				fmtAmt(summ.DebitAmt), fmtAmt(summ.CreditAmt),
+				fmtAmt(summ.CreditAmt - summ.DebitAmt),
+			}
+
+			for _, ccy := range ccys {
+				net := ""
+				if a, ok := summ.ByCurrency[ccy]; ok {
+					net = fmtAmt(a.CreditAmt - a.DebitAmt)
+				}
+
+				rec = append(rec, net)
+			}
+
+			return cw.Write(rec)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// reportJSON writes the summaries for the categories as a JSON array with
+// an entry for each category holding the tree of its components
+func (s *summaries) reportJSON(prog *prog, w io.Writer) error {
+	trees := []*jsonSummary{}
+
+	for _, cat := range prog.showCats {
+		summ := s.Summary(cat)
+		if summ == nil {
+			return fmt.Errorf("category: %q is not recognised", cat)
+		}
+
+		if js := prog.toJSON(summ); js != nil {
+			trees = append(trees, js)
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(trees)
+}
+
+// mdEscape escapes the characters which would break a Markdown table
+func mdEscape(s string) string {
+	return strings.NewReplacer("|", `\|`, "*", `\*`, "_", `\_`).Replace(s)
+}
+
+// reportMarkdown writes the summaries for each category as a Markdown
+// table. The names are indented with non-breaking spaces to show the tree.
+func (s *summaries) reportMarkdown(prog *prog, w io.Writer) error {
+	hdr := "| Transaction Type | Count | First | Last" +
+		" | Debit | Credit | Nett |"
+	align := "|:---|---:|:---|:---|---:|---:|---:|"
+
+	if prog.showOrigCcy {
+		hdr += " Nett by Currency |"
+		align += ":---|"
+	}
+
+	sep := ""
+
+	for _, cat := range prog.showCats {
+		summ := s.Summary(cat)
+		if summ == nil {
+			return fmt.Errorf("category: %q is not recognised", cat)
+		}
+
+		_, err := fmt.Fprint(w, sep+hdr+"\n"+align+"\n")
+		if err != nil {
+			return err
+		}
+
+		sep = "\n"
+
+		err = prog.walk(summ, 0, func(summ *bankac.Summary, depth int) error {
+			row := fmt.Sprintf("| %s%s | %d | %s | %s | %s | %s | %s |",
+				strings.Repeat("&nbsp;", tabWidth*depth),
+				mdEscape(summ.Name),
+				summ.Count,
+				fmtDate(summ, true), fmtDate(summ, false),
+				fmtAmt(summ.DebitAmt), fmtAmt(summ.CreditAmt),
+				fmtAmt(summ.CreditAmt-summ.DebitAmt))
+
+			if prog.showOrigCcy {
+				row += " " + currencyTotals(summ) + " |"
+			}
+
+			_, err := fmt.Fprintln(w, row)
+
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reportText writes the standard column-aligned reports for each category
+func (s *summaries) reportText(prog *prog) {
+	if prog.dates.isSet() || prog.comparing() {
+		fmt.Fprintln(prog.out, "Period:", prog.dates)
+
+		if prog.comparing() {
+			fmt.Fprintln(prog.out, "Compared with:", prog.comparedWith())
+		}
+
+		fmt.Fprintln(prog.out)
+	}
+
+	if prog.budgetFileName != "" &&
+		(prog.dates.from.IsZero() || prog.dates.to.IsZero()) {
+		from, to := s.budgetDates(prog)
+		fmt.Fprintln(prog.out, "Budget period:", dateRange{from: from, to: to})
+		fmt.Fprintln(prog.out)
+	}
+
+	sep := ""
+	for _, cat := range prog.showCats {
+		fmt.Fprint(prog.out, sep)
+		sep = "\n"
+
+		switch {
+		case prog.budgetFileName != "":
+			s.budgetReport(prog, cat)
+		case prog.comparing():
+			s.compareReport(prog, cat)
+		case prog.listXactns:
+			s.listReport(prog, cat)
+		case prog.recurring:
+			s.recurringReport(prog, cat)
+		case prog.chart:
+			s.chartReport(prog, cat)
+		case prog.tagReport:
+			s.tagReport(prog, cat)
+		case prog.anomalyReport:
+			s.anomalyReport(prog, cat)
+		case prog.periodBy != periodNone:
+			s.periodReport(prog, cat)
+		default:
+			s.report(prog, cat)
+		}
+	}
+}
+
+// textReports returns the names of the chosen reports which can only be
+// written as text
+func (prog *prog) textReports() []string {
+	rpts := []string{}
+
+	if prog.periodBy != periodNone {
+		rpts = append(rpts, "period")
+	}
+
+	if prog.budgetFileName != "" {
+		rpts = append(rpts, "budget")
+	}
+
+	if prog.recurring {
+		rpts = append(rpts, "recurring payments")
+	}
+
+	if prog.listXactns {
+		rpts = append(rpts, "transactions listing")
+	}
+
+	if prog.editReport {
+		rpts = append(rpts, "edit")
+	}
+
+	if prog.comparing() {
+		rpts = append(rpts, "comparison")
+	}
+
+	if prog.chart {
+		rpts = append(rpts, "chart")
+	}
+
+	if prog.tagReport {
+		rpts = append(rpts, "tag")
+	}
+
+	if prog.forecast {
+		rpts = append(rpts, "forecast")
+	}
+
+	if prog.anomalyReport {
+		rpts = append(rpts, "anomaly")
+	}
+
+	return rpts
+}
+
+// checkReports returns an error if more than one of the text-only reports
+// has been chosen or if one of them is to be written in another format
+func (prog *prog) checkReports() error {
+	rpts := prog.textReports()
+
+	if len(rpts) > 1 {
+		return fmt.Errorf("only one report can be chosen, not: %s",
+			strings.Join(rpts, ", "))
+	}
+
+	if len(rpts) == 1 && prog.outputFormat != outText {
+		return fmt.Errorf("the %s report can only be written as text",
+			rpts[0])
+	}
+
+	return nil
+}
+
+// writeReport writes the report in the chosen format
+func (prog *prog) writeReport(s *summaries) error {
+	switch prog.outputFormat {
+	case outCSV:
+		return s.reportCSV(prog, prog.out)
+	case outJSON:
+		return s.reportJSON(prog, prog.out)
+	case outMarkdown:
+		return s.reportMarkdown(prog, prog.out)
+	}
+
+	if prog.editReport {
+		s.editReport(prog)
+		return nil
+	}
+
+	if prog.forecast {
+		return s.forecastReport(prog)
+	}
+
+	s.reportText(prog)
+
+	return nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nickwells/personal-utils/bankACAnalysis/bankac"
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+// writeTestFile writes the content to the named file in the directory and
+// returns the full name of the file
+func writeTestFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+
+	fileName := filepath.Join(dir, name)
+	if err := os.WriteFile(fileName, []byte(content), 0o600); err != nil {
+		t.Fatal("couldn't write the test file:", err)
+	}
+
+	return fileName
+}
+
+// captureStderr calls the function with os.Stderr redirected and returns
+// whatever was written to it
+func captureStderr(t *testing.T, f func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal("couldn't create the pipe:", err)
+	}
+
+	origStderr := os.Stderr
+	os.Stderr = w
+
+	defer func() { os.Stderr = origStderr }()
+
+	captured := make(chan string)
+
+	go func() {
+		b, _ := io.ReadAll(r)
+		captured <- string(b)
+	}()
+
+	f()
+
+	w.Close()
+
+	return <-captured
+}
+
+func TestJSONOutputWithWarnings(t *testing.T) {
+	dir := t.TempDir()
+
+	const header = "Date,Type,Sort Code,Account,Description," +
+		"Debit,Credit,Balance\n"
+
+	prog := newProg()
+	prog.xactMapFileName = writeTestFile(t, dir, "map",
+		"all food\nfood TESCO\n")
+	prog.editFileName = writeTestFile(t, dir, "edits", "")
+	prog.files = []string{
+		writeTestFile(t, dir, "jan.csv", header+
+			"01/01/2024,DD,11-22-33,123,TESCO,10.00,,90.00\n"+
+			"02/01/2024,DD,11-22-33,123,TESCO,5.00,,85.00\n"),
+		writeTestFile(t, dir, "feb.csv", header+
+			"02/01/2024,DD,11-22-33,123,TESCO,5.00,,85.00\n"+
+			"not a date,DD,11-22-33,123,TESCO,1.00,,84.00\n"+
+			"03/02/2024,DD,11-22-33,123,CAFE,3.00,,82.00\n"),
+	}
+	prog.reportCurrency = prog.currency
+	prog.outputFormat = outJSON
+
+	var out bytes.Buffer
+
+	prog.out = &out
+
+	var reportErr error
+
+	warnings := captureStderr(t, func() {
+		var s *summaries
+
+		s, reportErr = prog.getAccountData()
+		if reportErr == nil {
+			reportErr = prog.writeReport(s)
+		}
+	})
+
+	if reportErr != nil {
+		t.Fatal("unexpected error:", reportErr)
+	}
+
+	for _, expWarning := range []string{
+		"Overlapping transactions",
+		"couldn't parse the date",
+	} {
+		if !strings.Contains(warnings, expWarning) {
+			t.Errorf("the warning %q was not written to stderr, got:\n%s",
+				expWarning, warnings)
+		}
+	}
+
+	var report any
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Errorf("the JSON output could not be parsed: %s\n%s",
+			err, out.String())
+	}
+}
+
+func TestOrigCurrencyOutput(t *testing.T) {
+	tree := bankac.NewTree()
+	if err := tree.AddParent(bankac.CatAll, "travel"); err != nil {
+		t.Fatal("couldn't add the category:", err)
+	}
+
+	s := &summaries{
+		Categoriser: bankac.NewCategoriser(bankac.NewSummaries(tree)),
+	}
+
+	for _, xa := range []bankac.Xactn{
+		{
+			Date:         mkDate(2024, time.March, 1),
+			DebitAmt:     10,
+			Currency:     "GBP",
+			OrigDebitAmt: 10,
+		},
+		{
+			Date:         mkDate(2024, time.March, 2),
+			DebitAmt:     20,
+			Currency:     "EUR",
+			OrigDebitAmt: 25,
+		},
+	} {
+		if err := s.Add("travel", xa, nil); err != nil {
+			t.Fatal("couldn't add the transaction:", err)
+		}
+	}
+
+	testCases := []struct {
+		testhelper.ID
+		report func(*prog, io.Writer) error
+		expOut string
+	}{
+		{
+			ID:     testhelper.MkID("csv"),
+			report: s.reportCSV,
+			expOut: "category,parent,depth,count,first date,last date," +
+				"debit,credit,net,net EUR,net GBP\n" +
+				"all,,0,2,2024-03-01,2024-03-02," +
+				"30.00,0.00,-30.00,-25.00,-10.00\n" +
+				"travel,all,1,2,2024-03-01,2024-03-02," +
+				"30.00,0.00,-30.00,-25.00,-10.00\n",
+		},
+		{
+			ID:     testhelper.MkID("markdown"),
+			report: s.reportMarkdown,
+			expOut: "| Transaction Type | Count | First | Last" +
+				" | Debit | Credit | Nett | Nett by Currency |\n" +
+				"|:---|---:|:---|:---|---:|---:|---:|:---|\n" +
+				"| all | 2 | 2024-03-01 | 2024-03-02" +
+				" | 30.00 | 0.00 | -30.00 | EUR -25.00, GBP -10.00 |\n" +
+				"| &nbsp;&nbsp;&nbsp;&nbsp;travel | 2 | 2024-03-01" +
+				" | 2024-03-02 | 30.00 | 0.00 | -30.00" +
+				" | EUR -25.00, GBP -10.00 |\n",
+		},
+	}
+
+	prog := newProg()
+	prog.showOrigCcy = true
+
+	for _, tc := range testCases {
+		var out strings.Builder
+
+		if err := tc.report(prog, &out); err != nil {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: unexpected error: %s", err)
+
+			continue
+		}
+
+		testhelper.DiffString(t, tc.IDStr(), "output", out.String(), tc.expOut)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"github.com/nickwells/param.mod/v7/param"
 	"github.com/nickwells/param.mod/v7/paramset"
+	"github.com/nickwells/verbose.mod/verbose"
 	"github.com/nickwells/versionparams.mod/versionparams"
 )
 
@@ -10,6 +11,7 @@ import (
 func makeParamSet(prog *prog) *param.PSet {
 	return paramset.New(
 		versionparams.AddParams,
+		verbose.AddParams,
 
 		addParams(prog),
 
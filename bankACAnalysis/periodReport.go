@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nickwells/col.mod/v6/col"
+	"github.com/nickwells/col.mod/v6/colfmt"
+	"github.com/nickwells/personal-utils/bankACAnalysis/bankac"
+)
+
+// The periods over which the report can be broken down
+const (
+	periodNone    = "none"
+	periodMonth   = "month"
+	periodQuarter = "quarter"
+	periodYear    = "year"
+)
+
+// The values that can be shown for each period
+const (
+	valDebit  = "debit"
+	valCredit = "credit"
+	valNet    = "net"
+)
+
+// monthsPerPeriod gives the number of months in each period
+var monthsPerPeriod = map[string]int{
+	periodMonth:   1,
+	periodQuarter: 3,
+	periodYear:    12,
+}
+
+// periodStart returns the first day of the period containing the time
+func periodStart(t time.Time, period string) time.Time {
+	y, m, _ := t.Date()
+
+	months := monthsPerPeriod[period]
+	m = ((m-1)/time.Month(months))*time.Month(months) + 1
+
+	return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// nextPeriod returns the start of the period following the one starting at
+// the given time
+func nextPeriod(start time.Time, period string) time.Time {
+	return start.AddDate(0, monthsPerPeriod[period], 0)
+}
+
+// periodName returns the name of the period starting at the given time
+func periodName(start time.Time, period string) string {
+	switch period {
+	case periodQuarter:
+		return fmt.Sprintf("%d-Q%d", start.Year(), (start.Month()-1)/3+1)
+	case periodYear:
+		return start.Format("2006")
+	}
+
+	return start.Format("2006-Jan")
+}
+
+// amountValue returns the debit, credit or net amount
+func amountValue(a bankac.Amounts, v string) float64 {
+	switch v {
+	case valDebit:
+		return a.DebitAmt
+	case valCredit:
+		return a.CreditAmt
+	}
+
+	return a.CreditAmt - a.DebitAmt
+}
+
+// valueName returns the column heading for the value
+func valueName(v string) string {
+	switch v {
+	case valDebit:
+		return "Debit"
+	case valCredit:
+		return "Credit"
+	}
+
+	return "Nett"
+}
+
+// periodAmounts returns the totals of the transactions for the Summary in
+// the period starting at the given time
+func periodAmounts(s *bankac.Summary, start time.Time, period string,
+) bankac.Amounts {
+	return s.AmountsBetween(start, nextPeriod(start, period))
+}
+
+// periods returns the start of each period from the first to the last
+// transaction
+func (s *summaries) periods(period string) []time.Time {
+	all := s.Summary(bankac.CatAll)
+	if all.Count == 0 {
+		return nil
+	}
+
+	starts := []time.Time{}
+
+	last := periodStart(all.LastDate, period)
+
+	for p := periodStart(all.FirstDate, period); !p.After(last); {
+		starts = append(starts, p)
+		p = nextPeriod(p, period)
+	}
+
+	return starts
+}
+
+// periodReport will report the summaries broken down by period, one column
+// per period
+func (s *summaries) periodReport(prog *prog, cat string) {
+	const (
+		floatColWidth = 10
+		floatColPrec  = 2
+	)
+
+	summ := s.Summary(cat)
+	if summ == nil {
+		fmt.Fprintf(os.Stderr, "*** category: %q is not recognised\n", cat)
+		return
+	}
+
+	floatCol := colfmt.Float{
+		W:    floatColWidth,
+		Prec: floatColPrec,
+		Zeroes: &colfmt.FloatZeroHandler{
+			Handle:  true,
+			Replace: "",
+		},
+	}
+
+	starts := s.periods(prog.periodBy)
+	vName := valueName(prog.periodValue)
+
+	cols := []*col.Col{}
+	for _, start := range starts {
+		cols = append(cols,
+			col.New(&floatCol, vName, periodName(start, prog.periodBy)))
+	}
+
+	cols = append(cols, col.New(&floatCol, vName, "Total"))
+
+	rpt := col.NewReportOrPanic(col.NewHeaderOrPanic(), prog.out,
+		col.New(&colfmt.String{W: tabWidth*s.MaxDepth() + s.MaxNameWidth()},
+			"Transaction Type"),
+		cols...)
+
+	prog.periodRows(summ, rpt, starts, 0)
+}
+
+// periodRows prints the row for the Summary, with a column for each
+// period, and then the rows for its components
+func (prog *prog) periodRows(
+	s *bankac.Summary,
+	rpt *col.Report,
+	starts []time.Time,
+	indent int,
+) {
+	if prog.isHidden(s) {
+		return
+	}
+
+	vals := []any{strings.Repeat(" ", tabWidth*indent) + s.Name}
+	for _, start := range starts {
+		vals = append(vals,
+			amountValue(periodAmounts(s, start, prog.periodBy),
+				prog.periodValue))
+	}
+
+	vals = append(vals, amountValue(bankac.Amounts{
+		Count:     s.Count,
+		DebitAmt:  s.DebitAmt,
+		CreditAmt: s.CreditAmt,
+	}, prog.periodValue))
+
+	err := rpt.PrintRow(vals...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Couldn't print the row:", err)
+	}
+
+	for _, c := range s.SortedComponents() {
+		prog.periodRows(c, rpt, starts, indent+1)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/nickwells/personal-utils/bankACAnalysis/bankac"
+)
+
+// fmtAuto is the file format to give if the format of each bank account
+// file is to be chosen from its extension
+const fmtAuto = "auto"
+
+// newReader returns the reader of the bank account files. Entries in the
+// files which cannot be converted into transactions are reported.
+func newReader() *bankac.Reader {
+	rd := bankac.NewReader()
+	rd.BadEntry = func(err error) { fmt.Fprintln(os.Stderr, err) }
+
+	return rd
+}
+
+// formatOf returns the format of the named file. If the format has been
+// given explicitly that is used otherwise it is chosen from the file
+// extension with any unrecognised extension being taken as CSV.
+func (prog *prog) formatOf(name string) string {
+	if prog.fileFormat != fmtAuto {
+		return prog.fileFormat
+	}
+
+	return bankac.FormatOf(name)
+}
+
+// readXactns opens the named file and reads the transactions from it using
+// the reader for the format of the file
+func (prog *prog) readXactns(name string) ([]Xactn, error) {
+	f, err := openFile(name, "bank account")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	bxas, err := prog.reader.Read(prog.formatOf(name), name, f)
+	if err != nil {
+		return nil, err
+	}
+
+	xas := make([]Xactn, 0, len(bxas))
+	for _, bxa := range bxas {
+		xas = append(xas, Xactn{Xactn: bxa})
+	}
+
+	return xas, nil
+}
+
+// setLayouts parses the layout definitions and selects the chosen layout
+func (prog *prog) setLayouts() error {
+	prog.layouts = map[string]bankac.CSVLayout{
+		bankac.DfltLayoutName: bankac.DfltLayout(),
+	}
+
+	for _, def := range prog.layoutDefs {
+		l, err := bankac.ParseCSVLayout(def)
+		if err != nil {
+			return err
+		}
+
+		prog.layouts[l.Name()] = l
+	}
+
+	l, ok := prog.layouts[prog.layoutName]
+	if !ok {
+		names := []string{}
+		for name := range prog.layouts {
+			names = append(names, name)
+		}
+
+		slices.Sort(names)
+
+		return fmt.Errorf("there is no CSV layout called %q, choose from: %s",
+			prog.layoutName, strings.Join(names, ", "))
+	}
+
+	if l.UsesHeader() && !prog.reader.SkipFirstLine {
+		return errors.New("the CSV layout " + l.Name() +
+			" gives columns by their header names but the first line" +
+			" of the transactions file is not to be skipped")
+	}
+
+	prog.reader.Layout = l
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"slices"
+)
+
+const (
+	// balanceTolerance is the largest difference between balances which
+	// is ignored, it allows for rounding errors
+	balanceTolerance = 0.005
+
+	hoursPerDay    = 24
+	dfltMaxGapDays = 7
+)
+
+// fileXactns holds the transactions from a single file in date order
+type fileXactns struct {
+	name string
+	xas  []Xactn
+}
+
+// first returns the first transaction in the file
+func (fx fileXactns) first() Xactn { return fx.xas[0] }
+
+// last returns the last transaction in the file
+func (fx fileXactns) last() Xactn { return fx.xas[len(fx.xas)-1] }
+
+// openingBalance returns the balance before the first transaction. It is
+// calculated from the first transaction with a balance.
+func (fx fileXactns) openingBalance() float64 {
+	net := 0.0
+
+	for _, xa := range fx.xas {
+		net += xa.DebitAmt - xa.CreditAmt
+		if xa.HasBalance {
+			return xa.Balance + net
+		}
+	}
+
+	return net
+}
+
+// closingBalance returns the balance after the last transaction. It is
+// calculated from the last transaction with a balance.
+func (fx fileXactns) closingBalance() float64 {
+	net := 0.0
+
+	for _, xa := range slices.Backward(fx.xas) {
+		if xa.HasBalance {
+			return xa.Balance + net
+		}
+
+		net += xa.CreditAmt - xa.DebitAmt
+	}
+
+	return net
+}
+
+// inDateOrder returns a copy of the transactions sorted by date. Bank files
+// often give the most recent transaction first so if the first transaction
+// is later than the last the order is reversed before sorting; this keeps
+// the transactions on each day in the order in which they happened.
+func inDateOrder(xas []Xactn) []Xactn {
+	sorted := slices.Clone(xas)
+
+	if len(sorted) > 1 && sorted[0].Date.After(sorted[len(sorted)-1].Date) {
+		slices.Reverse(sorted)
+	}
+
+	slices.SortStableFunc(sorted, func(a, b Xactn) int {
+		return a.Date.Compare(b.Date)
+	})
+
+	return sorted
+}
+
+// balancesDiffer returns true if the balances are not the same (to within
+// the tolerance)
+func balancesDiffer(a, b float64) bool {
+	return math.Abs(a-b) > balanceTolerance
+}
+
+// reconcileFile checks that each balance in the file is equal to the
+// previous balance plus the credits less the debits since then. Transactions
+// without a balance are not checked but their amounts are included in the
+// next balance. It reports any mismatches and returns the number found.
+func reconcileFile(fx fileXactns) int {
+	var (
+		errCount int
+		prev     *Xactn
+		expected float64
+	)
+
+	for i, xa := range fx.xas {
+		expected += xa.CreditAmt - xa.DebitAmt
+
+		if !xa.HasBalance {
+			continue
+		}
+
+		if prev != nil && balancesDiffer(expected, xa.Balance) {
+			fmt.Fprintf(os.Stderr, "%s:%d: balance mismatch:"+
+				" expected: %.2f, found: %.2f (difference: %.2f)"+
+				" - previous balance at line %d\n",
+				fx.name, xa.LineNum,
+				expected, xa.Balance, xa.Balance-expected,
+				prev.LineNum)
+
+			errCount++
+		}
+
+		prev, expected = &fx.xas[i], xa.Balance
+	}
+
+	return errCount
+}
+
+// checkFileGap checks the boundary between two consecutive files for the
+// same account. It reports if the closing balance of the first does not
+// match the opening balance of the second or if there are more than
+// maxGapDays days between the last transaction of one and the first
+// transaction of the other. It returns the number of problems found.
+func checkFileGap(prev, next fileXactns, maxGapDays int) int {
+	errCount := 0
+	pLast, nFirst := prev.last(), next.first()
+
+	if !nFirst.Date.After(pLast.Date) {
+		return 0 // the files overlap so there is no gap
+	}
+
+	if balancesDiffer(prev.closingBalance(), next.openingBalance()) {
+		fmt.Fprintf(os.Stderr,
+			"%s:%d: balance gap: the closing balance at %s:%d"+
+				" is %.2f but the opening balance is %.2f"+
+				" (difference: %.2f)\n",
+			next.name, nFirst.LineNum,
+			prev.name, pLast.LineNum,
+			prev.closingBalance(), next.openingBalance(),
+			next.openingBalance()-prev.closingBalance())
+
+		errCount++
+	}
+
+	days := int(nFirst.Date.Sub(pLast.Date).Hours() / hoursPerDay)
+	if days > maxGapDays {
+		fmt.Fprintf(os.Stderr,
+			"%s:%d: date gap: %d days since the last transaction"+
+				" at %s:%d (%s to %s)\n",
+			next.name, nFirst.LineNum,
+			days, prev.name, pLast.LineNum,
+			pLast.Date.Format(rptDateFormat),
+			nFirst.Date.Format(rptDateFormat))
+
+		errCount++
+	}
+
+	return errCount
+}
+
+// reconcile checks the running balances in each file and the gaps between
+// consecutive files for the same account. Files without balances are
+// skipped, as are the transactions without a balance in the other files.
+func (prog *prog) reconcile(byFile map[string][]Xactn) {
+	byAcct := map[string][]fileXactns{}
+	accts := []string{}
+	errCount := 0
+
+	for _, name := range prog.files {
+		xas := byFile[name]
+		if !slices.ContainsFunc(xas, func(xa Xactn) bool {
+			return xa.HasBalance
+		}) {
+			continue
+		}
+
+		fx := fileXactns{name: name, xas: inDateOrder(xas)}
+		errCount += reconcileFile(fx)
+
+		acct := xas[0].Account
+		if _, ok := byAcct[acct]; !ok {
+			accts = append(accts, acct)
+		}
+
+		byAcct[acct] = append(byAcct[acct], fx)
+	}
+
+	for _, acct := range accts {
+		files := byAcct[acct]
+		slices.SortStableFunc(files, func(a, b fileXactns) int {
+			return a.first().Date.Compare(b.first().Date)
+		})
+
+		for i := 1; i < len(files); i++ {
+			errCount += checkFileGap(files[i-1], files[i], prog.maxGapDays)
+		}
+	}
+
+	if errCount > 0 {
+		fmt.Fprintf(os.Stderr,
+			"%d reconciliation problem(s) found\n\n", errCount)
+	}
+}
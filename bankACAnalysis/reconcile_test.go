@@ -0,0 +1,188 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nickwells/personal-utils/bankACAnalysis/bankac"
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+// balLine gives the day, the signed amount and the balance of a
+// transaction; a balance of noBal means that no balance is given
+type balLine struct {
+	day int
+	amt float64
+	bal float64
+}
+
+const noBal = -1
+
+// mkBalFile returns the file of transactions with the given amounts and
+// balances
+func mkBalFile(name string, lines ...balLine) fileXactns {
+	fx := fileXactns{name: name}
+
+	for i, l := range lines {
+		xa := Xactn{Xactn: bankac.Xactn{
+			FileName: name,
+			LineNum:  i + 1,
+			Date:     mkDate(2024, time.March, l.day),
+		}}
+		xa.SetAmount(l.amt)
+
+		if l.bal != noBal {
+			xa.Balance, xa.HasBalance = l.bal, true
+		}
+
+		fx.xas = append(fx.xas, xa)
+	}
+
+	return fx
+}
+
+func TestReconcileFile(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		fx          fileXactns
+		expErrs     int
+		expWarnings []string
+	}{
+		{
+			ID: testhelper.MkID("balances agree"),
+			fx: mkBalFile("a",
+				balLine{1, -10, 90},
+				balLine{2, 50, 140},
+				balLine{3, -40, 100}),
+		},
+		{
+			ID: testhelper.MkID("mismatch"),
+			fx: mkBalFile("a",
+				balLine{1, -10, 90},
+				balLine{2, 50, 150},
+				balLine{3, -40, 110}),
+			expErrs: 1,
+			expWarnings: []string{
+				"a:2: balance mismatch: expected: 140.00, found: 150.00" +
+					" (difference: 10.00) - previous balance at line 1",
+			},
+		},
+		{
+			ID: testhelper.MkID("some lines without a balance"),
+			fx: mkBalFile("a",
+				balLine{1, -10, noBal},
+				balLine{2, 50, 140},
+				balLine{3, -40, noBal},
+				balLine{4, -5, noBal},
+				balLine{5, -5, 90}),
+		},
+		{
+			ID: testhelper.MkID("mismatch after lines without a balance"),
+			fx: mkBalFile("a",
+				balLine{1, -10, 90},
+				balLine{2, 50, noBal},
+				balLine{3, -40, 110}),
+			expErrs: 1,
+			expWarnings: []string{
+				"a:3: balance mismatch: expected: 100.00, found: 110.00" +
+					" (difference: 10.00) - previous balance at line 1",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		var errCount int
+
+		warnings := captureStderr(t, func() {
+			errCount = reconcileFile(tc.fx)
+		})
+
+		testhelper.DiffInt(t, tc.IDStr(), "errors", errCount, tc.expErrs)
+
+		for _, expWarning := range tc.expWarnings {
+			if !strings.Contains(warnings, expWarning) {
+				t.Log(tc.IDStr())
+				t.Errorf("\t: the warning %q was not reported, got:\n%s",
+					expWarning, warnings)
+			}
+		}
+	}
+}
+
+func TestCheckFileGap(t *testing.T) {
+	const maxGapDays = 7
+
+	prev := mkBalFile("a",
+		balLine{1, -10, 90},
+		balLine{2, -10, 80},
+		balLine{3, -5, noBal})
+
+	testCases := []struct {
+		testhelper.ID
+		next        fileXactns
+		expErrs     int
+		expWarnings []string
+	}{
+		{
+			ID: testhelper.MkID("no gap"),
+			next: mkBalFile("b",
+				balLine{4, -10, 65}),
+		},
+		{
+			ID: testhelper.MkID("opening balance from a later line"),
+			next: mkBalFile("b",
+				balLine{4, -10, noBal},
+				balLine{5, 20, 85}),
+		},
+		{
+			ID: testhelper.MkID("balance gap"),
+			next: mkBalFile("b",
+				balLine{4, -10, 60}),
+			expErrs: 1,
+			expWarnings: []string{
+				"b:1: balance gap: the closing balance at a:3 is 75.00" +
+					" but the opening balance is 70.00" +
+					" (difference: -5.00)",
+			},
+		},
+		{
+			ID: testhelper.MkID("date gap"),
+			next: mkBalFile("b",
+				balLine{11, -10, 65}),
+			expErrs: 1,
+			expWarnings: []string{
+				"b:1: date gap: 8 days since the last transaction at a:3",
+			},
+		},
+		{
+			ID: testhelper.MkID("balance and date gaps"),
+			next: mkBalFile("b",
+				balLine{20, -10, 0}),
+			expErrs: 2,
+		},
+		{
+			ID: testhelper.MkID("files overlap"),
+			next: mkBalFile("b",
+				balLine{3, -5, 0}),
+		},
+	}
+
+	for _, tc := range testCases {
+		var errCount int
+
+		warnings := captureStderr(t, func() {
+			errCount = checkFileGap(prev, tc.next, maxGapDays)
+		})
+
+		testhelper.DiffInt(t, tc.IDStr(), "errors", errCount, tc.expErrs)
+
+		for _, expWarning := range tc.expWarnings {
+			if !strings.Contains(warnings, expWarning) {
+				t.Log(tc.IDStr())
+				t.Errorf("\t: the warning %q was not reported, got:\n%s",
+					expWarning, warnings)
+			}
+		}
+	}
+}
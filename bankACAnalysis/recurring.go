@@ -0,0 +1,327 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/nickwells/col.mod/v6/col"
+	"github.com/nickwells/col.mod/v6/colfmt"
+	"github.com/nickwells/personal-utils/bankACAnalysis/bankac"
+)
+
+const (
+	// dfltRecurringMinCount is the default for the smallest number of
+	// payments that are taken as recurring
+	dfltRecurringMinCount = 3
+
+	// recurringAmtTolerance is the largest proportion by which a payment
+	// may differ from the typical amount of the payments either side of a
+	// change in price and still be taken as recurring
+	recurringAmtTolerance = 0.25
+
+	// recurringIntervalShare is the smallest proportion of the intervals
+	// between payments that must be of the expected length
+	recurringIntervalShare = 0.75
+
+	amountChangedFlag = "CHANGED"
+)
+
+// frequency describes how often a recurring payment is made. An interval
+// between payments of between minDays and maxDays is taken to be of this
+// frequency and the next payment is expected after the given number of
+// months and days.
+type frequency struct {
+	name    string
+	minDays float64
+	maxDays float64
+	months  int
+	days    int
+}
+
+// frequencies lists the frequencies of recurring payments that are detected
+var frequencies = []frequency{
+	{name: "weekly", minDays: 6, maxDays: 8, days: 7},
+	{name: "fortnightly", minDays: 13, maxDays: 15, days: 14},
+	{name: "monthly", minDays: 26, maxDays: 35, months: 1},
+	{name: "quarterly", minDays: 84, maxDays: 98, months: 3},
+	{name: "yearly", minDays: 350, maxDays: 380, months: 12},
+}
+
+// next returns the date on which the payment after the given date is
+// expected
+func (f frequency) next(t time.Time) time.Time {
+	return t.AddDate(0, f.months, f.days)
+}
+
+// recurring records a payment, or a receipt if credit is set, which has
+// been made at regular intervals
+type recurring struct {
+	desc       string
+	credit     bool
+	count      int
+	freq       frequency
+	typicalAmt float64
+	lastAmt    float64
+	lastDate   time.Time
+	changed    bool
+}
+
+// median returns the median of the values
+func median(vals []float64) float64 {
+	sorted := slices.Clone(vals)
+	slices.Sort(sorted)
+
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// findFrequency returns the frequency of the payments, given the intervals
+// in days between them. It returns false if the intervals are not regular
+// enough to match any frequency.
+func findFrequency(intervals []float64) (frequency, bool) {
+	mid := median(intervals)
+
+	for _, f := range frequencies {
+		if mid < f.minDays || mid > f.maxDays {
+			continue
+		}
+
+		inRange := 0
+
+		for _, days := range intervals {
+			if days >= f.minDays && days <= f.maxDays {
+				inRange++
+			}
+		}
+
+		share := float64(inRange) / float64(len(intervals))
+		if share >= recurringIntervalShare {
+			return f, true
+		}
+	}
+
+	return frequency{}, false
+}
+
+// amountOf returns the credit amount of the transaction if credit is set
+// and otherwise its debit amount
+func amountOf(xa Xactn, credit bool) float64 {
+	if credit {
+		return xa.CreditAmt
+	}
+
+	return xa.DebitAmt
+}
+
+// steadyAmounts returns true if each of the amounts is within
+// recurringAmtTolerance of their median
+func steadyAmounts(amts []float64) bool {
+	typical := median(amts)
+
+	for _, amt := range amts {
+		if math.Abs(amt-typical) > recurringAmtTolerance*typical {
+			return false
+		}
+	}
+
+	return true
+}
+
+// separate returns true if all of the amounts after are greater than, or
+// all are less than, all of the amounts before
+func separate(before, after []float64) bool {
+	return slices.Min(after) > slices.Max(before)+balanceTolerance ||
+		slices.Max(after) < slices.Min(before)-balanceTolerance
+}
+
+// regularAmounts returns the index of the first payment after a change in
+// the amount, as when the price of a subscription rises, or the number of
+// amounts if there is no change. The amounts either side of a change must
+// be steady and all of those after the change must be above, or all below,
+// those before it. If there is more than one such change the largest is
+// taken. It returns false if the amounts are neither steady nor change just
+// once.
+func regularAmounts(amts []float64) (int, bool) {
+	change, biggest := len(amts), 0.0
+
+	for i := 1; i < len(amts); i++ {
+		before, after := amts[:i], amts[i:]
+		if !steadyAmounts(before) || !steadyAmounts(after) ||
+			!separate(before, after) {
+			continue
+		}
+
+		if diff := math.Abs(median(after) - median(before)); diff >= biggest {
+			change, biggest = i, diff
+		}
+	}
+
+	if change < len(amts) {
+		return change, true
+	}
+
+	return change, steadyAmounts(amts)
+}
+
+// findRecurring returns the details of the payments, or of the receipts if
+// credit is set, if they recur at a regular frequency and for similar
+// amounts, allowing for a single change in the amount. The typical amount
+// is that of the payments before any change and the payments are flagged
+// as changed if the last amount differs from it. The transactions must be
+// in date order. It returns false if they do not recur.
+func findRecurring(
+	desc string, xas []Xactn, minCount int, credit bool,
+) (recurring, bool) {
+	if len(xas) < minCount {
+		return recurring{}, false
+	}
+
+	amts := make([]float64, 0, len(xas))
+	intervals := make([]float64, 0, len(xas)-1)
+
+	for i, xa := range xas {
+		amts = append(amts, amountOf(xa, credit))
+
+		if i > 0 {
+			intervals = append(intervals,
+				xa.Date.Sub(xas[i-1].Date).Hours()/hoursPerDay)
+		}
+	}
+
+	freq, ok := findFrequency(intervals)
+	if !ok {
+		return recurring{}, false
+	}
+
+	change, ok := regularAmounts(amts)
+	if !ok {
+		return recurring{}, false
+	}
+
+	typical := median(amts[:change])
+	lastAmt := amts[len(amts)-1]
+
+	return recurring{
+		desc:       desc,
+		credit:     credit,
+		count:      len(xas),
+		freq:       freq,
+		typicalAmt: typical,
+		lastAmt:    lastAmt,
+		lastDate:   xas[len(xas)-1].Date,
+		changed:    balancesDiffer(lastAmt, typical),
+	}, true
+}
+
+// recurringItems returns the recurring payments, or the recurring receipts
+// if credit is set, in the category sorted by description. The parts of a
+// split transaction in the category are taken together.
+func (s *summaries) recurringItems(
+	prog *prog, cat string, credit bool,
+) []recurring {
+	return findAllRecurring(prog,
+		bankac.MergeParts(s.categoryXactns(cat, listByDate)), credit)
+}
+
+// findAllRecurring returns the recurring payments, or the recurring
+// receipts if credit is set, among the transactions sorted by description.
+// Transfers between our own accounts are ignored.
+func findAllRecurring(prog *prog, xas []Xactn, credit bool) []recurring {
+	byDesc := map[string][]Xactn{}
+
+	for _, xa := range xas {
+		if amountOf(xa, credit) == 0 || xa.IsTransfer {
+			continue
+		}
+
+		byDesc[xa.Desc] = append(byDesc[xa.Desc], xa)
+	}
+
+	recs := []recurring{}
+
+	for desc, xas := range byDesc {
+		sort.SliceStable(xas, func(i, j int) bool {
+			return xas[i].Date.Before(xas[j].Date)
+		})
+
+		r, ok := findRecurring(desc, xas, prog.recurringMinCount, credit)
+		if ok {
+			recs = append(recs, r)
+		}
+	}
+
+	sort.Slice(recs, func(i, j int) bool {
+		return recs[i].desc < recs[j].desc
+	})
+
+	return recs
+}
+
+// recurringReport will report the payments in the category which recur at
+// regular intervals, showing the typical amount, how often the payment is
+// made and when the next payment is expected. Payments whose latest amount
+// differs from the typical amount are flagged.
+func (s *summaries) recurringReport(prog *prog, cat string) {
+	const (
+		floatColWidth = 10
+		floatColPrec  = 2
+		countColWidth = 5
+	)
+
+	if s.Summary(cat) == nil {
+		fmt.Fprintf(os.Stderr, "*** category: %q is not recognised\n", cat)
+		return
+	}
+
+	recs := s.recurringItems(prog, cat, false)
+
+	descWidth, freqWidth := len("Description"), 0
+	for _, r := range recs {
+		descWidth = max(descWidth, len(r.desc))
+	}
+
+	for _, f := range frequencies {
+		freqWidth = max(freqWidth, len(f.name))
+	}
+
+	floatCol := colfmt.Float{W: floatColWidth, Prec: floatColPrec}
+
+	rpt := col.NewReportOrPanic(col.NewHeaderOrPanic(), prog.out,
+		col.New(&colfmt.String{W: descWidth}, "Description"),
+		col.New(&colfmt.Int{W: countColWidth}, "Count"),
+		col.New(&colfmt.String{W: freqWidth}, "Frequency"),
+		col.New(&floatCol, "Typical", "Amount"),
+		col.New(&floatCol, "Last", "Amount"),
+		col.New(&colfmt.Time{Format: rptDateFormat}, "Last", "Date"),
+		col.New(&colfmt.Time{Format: rptDateFormat}, "Next", "Date"),
+		col.New(&colfmt.String{W: len(amountChangedFlag)}, ""),
+	)
+
+	for _, r := range recs {
+		flag := ""
+		if r.changed {
+			flag = amountChangedFlag
+		}
+
+		err := rpt.PrintRow(
+			r.desc,
+			r.count,
+			r.freq.name,
+			r.typicalAmt,
+			r.lastAmt,
+			r.lastDate,
+			r.freq.next(r.lastDate),
+			flag)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Couldn't print the row:", err)
+		}
+	}
+}
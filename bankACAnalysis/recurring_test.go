@@ -0,0 +1,118 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nickwells/personal-utils/bankACAnalysis/bankac"
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+// mkMonthlyXactns returns a debit for each of the amounts, paid on the
+// first of each month starting in January 2024
+func mkMonthlyXactns(amts ...float64) []Xactn {
+	xas := []Xactn{}
+
+	for i, amt := range amts {
+		xas = append(xas, Xactn{Xactn: bankac.Xactn{
+			Date:     mkDate(2024, time.January+time.Month(i), 1),
+			Desc:     "STREAMING",
+			DebitAmt: amt,
+		}})
+	}
+
+	return xas
+}
+
+func TestFindRecurring(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		xas        []Xactn
+		expOK      bool
+		expTypical float64
+		expLastAmt float64
+		expChanged bool
+	}{
+		{
+			ID:         testhelper.MkID("steady"),
+			xas:        mkMonthlyXactns(9.99, 9.99, 9.99, 9.99),
+			expOK:      true,
+			expTypical: 9.99,
+			expLastAmt: 9.99,
+		},
+		{
+			ID:         testhelper.MkID("price rise of more than 25%"),
+			xas:        mkMonthlyXactns(8, 8, 8, 8, 8, 12, 12),
+			expOK:      true,
+			expTypical: 8,
+			expLastAmt: 12,
+			expChanged: true,
+		},
+		{
+			ID:         testhelper.MkID("price rise after the first payment"),
+			xas:        mkMonthlyXactns(8, 12, 12, 12),
+			expOK:      true,
+			expTypical: 8,
+			expLastAmt: 12,
+			expChanged: true,
+		},
+		{
+			ID:         testhelper.MkID("price rise half way through"),
+			xas:        mkMonthlyXactns(8, 8, 12, 12),
+			expOK:      true,
+			expTypical: 8,
+			expLastAmt: 12,
+			expChanged: true,
+		},
+		{
+			ID:         testhelper.MkID("price rise of less than 25%"),
+			xas:        mkMonthlyXactns(9.99, 9.99, 9.99, 10.99, 10.99),
+			expOK:      true,
+			expTypical: 9.99,
+			expLastAmt: 10.99,
+			expChanged: true,
+		},
+		{
+			ID:         testhelper.MkID("varying amounts, no single change"),
+			xas:        mkMonthlyXactns(40, 42, 39, 40),
+			expOK:      true,
+			expTypical: 40,
+			expLastAmt: 40,
+		},
+		{
+			ID:  testhelper.MkID("amounts not regular"),
+			xas: mkMonthlyXactns(10, 40, 10, 40, 10),
+		},
+		{
+			ID: testhelper.MkID("intervals not regular"),
+			xas: []Xactn{
+				{Xactn: bankac.Xactn{Date: mkDate(2024, 1, 1), DebitAmt: 5}},
+				{Xactn: bankac.Xactn{Date: mkDate(2024, 1, 3), DebitAmt: 5}},
+				{Xactn: bankac.Xactn{Date: mkDate(2024, 3, 9), DebitAmt: 5}},
+				{Xactn: bankac.Xactn{Date: mkDate(2024, 3, 20), DebitAmt: 5}},
+			},
+		},
+		{
+			ID:  testhelper.MkID("too few"),
+			xas: mkMonthlyXactns(9.99, 9.99),
+		},
+	}
+
+	for _, tc := range testCases {
+		r, ok := findRecurring("STREAMING", tc.xas,
+			dfltRecurringMinCount, false)
+		if testhelper.DiffBool(t, tc.IDStr(), "recurring", ok, tc.expOK) ||
+			!ok {
+			continue
+		}
+
+		testhelper.DiffString(t, tc.IDStr(), "frequency",
+			r.freq.name, "monthly")
+		testhelper.DiffFloat(t, tc.IDStr(), "typical amount",
+			r.typicalAmt, tc.expTypical, 1e-9)
+		testhelper.DiffFloat(t, tc.IDStr(), "last amount",
+			r.lastAmt, tc.expLastAmt, 1e-9)
+		testhelper.DiffBool(t, tc.IDStr(), "changed",
+			r.changed, tc.expChanged)
+	}
+}
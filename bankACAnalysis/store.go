@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/nickwells/personal-utils/bankACAnalysis/bankac"
+)
+
+const storeDesc = "transaction store"
+
+// storedXactn is the form in which a transaction is held in the store. The
+// amounts are in the currency of the account and the description is as
+// given in the bank account file.
+type storedXactn struct {
+	File       string  `json:"file"`
+	Line       int     `json:"line"`
+	Date       string  `json:"date"`
+	Type       string  `json:"type,omitempty"`
+	Desc       string  `json:"desc"`
+	Debit      float64 `json:"debit,omitempty"`
+	Credit     float64 `json:"credit,omitempty"`
+	Balance    float64 `json:"balance,omitempty"`
+	HasBalance bool    `json:"hasBalance,omitempty"`
+	Account    string  `json:"account,omitempty"`
+	Currency   string  `json:"currency,omitempty"`
+}
+
+// toStored converts the transaction into the form held in the store
+func toStored(xa Xactn) storedXactn {
+	return storedXactn{
+		File:       xa.FileName,
+		Line:       xa.LineNum,
+		Date:       xa.Date.Format(paramDateFormat),
+		Type:       xa.Type,
+		Desc:       xa.Desc,
+		Debit:      xa.OrigDebitAmt,
+		Credit:     xa.OrigCreditAmt,
+		Balance:    xa.Balance,
+		HasBalance: xa.HasBalance,
+		Account:    xa.Account,
+		Currency:   xa.Currency,
+	}
+}
+
+// toXactn converts the stored transaction back into a transaction. The
+// amounts are still in the currency of the account.
+func (sx storedXactn) toXactn() (Xactn, error) {
+	date, err := time.Parse(paramDateFormat, sx.Date)
+	if err != nil {
+		return Xactn{}, fmt.Errorf("bad date: %w", err)
+	}
+
+	return Xactn{
+		Xactn: bankac.Xactn{
+			FileName:   sx.File,
+			LineNum:    sx.Line,
+			Date:       date,
+			Type:       sx.Type,
+			Desc:       sx.Desc,
+			DebitAmt:   sx.Debit,
+			CreditAmt:  sx.Credit,
+			Balance:    sx.Balance,
+			HasBalance: sx.HasBalance,
+			Account:    sx.Account,
+			Currency:   sx.Currency,
+		},
+	}, nil
+}
+
+// readStore reads the transactions from the store and converts their
+// amounts into the reporting currency. If the store does not exist and may
+// be missing then no transactions are returned, otherwise it is an error.
+func (prog *prog) readStore(mayBeMissing bool) ([]Xactn, error) {
+	f, err := os.Open(prog.storeFileName)
+	if err != nil {
+		if mayBeMissing && errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("couldn't open the %s: %w", storeDesc, err)
+	}
+	defer f.Close()
+
+	xas := []Xactn{}
+	sScanner := bufio.NewScanner(f)
+	lineNum := 0
+
+	for sScanner.Scan() {
+		lineNum++
+
+		var sx storedXactn
+
+		err := json.Unmarshal(sScanner.Bytes(), &sx)
+		if err == nil {
+			var xa Xactn
+
+			xa, err = sx.toXactn()
+			if err == nil {
+				xas = append(xas, xa)
+				continue
+			}
+		}
+
+		return nil, fmt.Errorf("%s:%d: bad entry in the %s: %w",
+			prog.storeFileName, lineNum, storeDesc, err)
+	}
+
+	if err := sScanner.Err(); err != nil {
+		return nil, fmt.Errorf("couldn't read the %s: %w", storeDesc, err)
+	}
+
+	return prog.setCurrencies(prog.storeFileName, xas), nil
+}
+
+// notInStore returns those transactions which are not already in the
+// store. A transaction which appears more than once is only taken to be
+// in the store as many times as it appears there.
+func notInStore(stored, xas []Xactn) []Xactn {
+	inStore := map[xactnKey]int{}
+	for _, xa := range stored {
+		inStore[keyOf(xa)]++
+	}
+
+	added := []Xactn{}
+
+	for _, xa := range xas {
+		k := keyOf(xa)
+		if inStore[k] > 0 {
+			inStore[k]--
+			continue
+		}
+
+		added = append(added, xa)
+	}
+
+	return added
+}
+
+// withStore returns the transactions from the store together with those
+// transactions which are not already in it
+func (prog *prog) withStore(xas []Xactn) ([]Xactn, error) {
+	stored, err := prog.readStore(false)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(stored, notInStore(stored, xas)...), nil
+}
+
+// importXactns reads the transactions from the files and appends those
+// which are not already in the store to it. Importing the same
+// transactions again has no effect.
+func (prog *prog) importXactns() error {
+	xas, err := prog.readFiles()
+	if err != nil {
+		return err
+	}
+
+	stored, err := prog.readStore(true)
+	if err != nil {
+		return err
+	}
+
+	added := notInStore(stored, xas)
+
+	f, err := os.OpenFile(prog.storeFileName,
+		os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("couldn't open the %s: %w", storeDesc, err)
+	}
+
+	enc := json.NewEncoder(f)
+
+	for _, xa := range added {
+		if err = enc.Encode(toStored(xa)); err != nil {
+			break
+		}
+	}
+
+	if cErr := f.Close(); cErr != nil && err == nil {
+		err = cErr
+	}
+
+	if err != nil {
+		return fmt.Errorf("couldn't write to the %s: %w", storeDesc, err)
+	}
+
+	fmt.Fprintf(os.Stderr,
+		"%d transaction(s) imported into %s, %d already present\n",
+		len(added), prog.storeFileName, len(xas)-len(added))
+
+	return nil
+}
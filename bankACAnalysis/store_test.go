@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nickwells/personal-utils/bankACAnalysis/bankac"
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestNotInStore(t *testing.T) {
+	tesco := Xactn{
+		Xactn: bankac.Xactn{
+			Date:     mkDate(2024, time.February, 1),
+			Desc:     "TESCO",
+			DebitAmt: 10.5,
+		},
+	}
+	cafe := Xactn{
+		Xactn: bankac.Xactn{
+			Date:     mkDate(2024, time.February, 1),
+			Desc:     "CAFE",
+			DebitAmt: 3,
+		},
+	}
+	otherTesco := tesco
+	otherTesco.Account = "11-22-33 456"
+
+	testCases := []struct {
+		testhelper.ID
+		stored, xas []Xactn
+		expCount    int
+	}{
+		{
+			ID:       testhelper.MkID("empty store"),
+			xas:      []Xactn{tesco, cafe},
+			expCount: 2,
+		},
+		{
+			ID:       testhelper.MkID("all already stored"),
+			stored:   []Xactn{tesco, cafe},
+			xas:      []Xactn{cafe, tesco},
+			expCount: 0,
+		},
+		{
+			ID:       testhelper.MkID("repeated transaction, stored once"),
+			stored:   []Xactn{tesco},
+			xas:      []Xactn{tesco, tesco, cafe},
+			expCount: 2,
+		},
+		{
+			ID:       testhelper.MkID("same payment from another account"),
+			stored:   []Xactn{tesco},
+			xas:      []Xactn{tesco, otherTesco},
+			expCount: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		added := notInStore(tc.stored, tc.xas)
+		testhelper.DiffInt(t, tc.IDStr(), "added", len(added), tc.expCount)
+
+		again := notInStore(append(tc.stored, added...), tc.xas)
+		testhelper.DiffInt(t, tc.IDStr(), "re-imported", len(again), 0)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/nickwells/col.mod/v6/col"
+	"github.com/nickwells/col.mod/v6/colfmt"
+)
+
+// tagTotal holds the totals of the transactions with a tag, either in all
+// categories or in just one. The locations of the transactions are
+// recorded so that the parts of a split transaction are only counted once.
+type tagTotal struct {
+	name      string
+	locations map[string]bool
+	debitAmt  float64
+	creditAmt float64
+}
+
+// add adds the transaction to the totals
+func (tt *tagTotal) add(xa Xactn) {
+	if tt.locations == nil {
+		tt.locations = map[string]bool{}
+	}
+
+	tt.locations[xa.Location()] = true
+	tt.debitAmt += xa.DebitAmt
+	tt.creditAmt += xa.CreditAmt
+}
+
+// tagTotals returns the totals for each tag of the transactions in the
+// category, in alphabetical order of the tags, and for each tag the
+// totals for each category, largest first
+func (s *summaries) tagTotals(
+	cat string,
+) ([]*tagTotal, map[string][]*tagTotal) {
+	byTag := map[string]*tagTotal{}
+	byTagCat := map[string]map[string]*tagTotal{}
+
+	for _, xa := range s.xactns {
+		if !s.Tree().InCategory(xa.SummName, cat) {
+			continue
+		}
+
+		for _, t := range xa.Tags {
+			tt, ok := byTag[t]
+			if !ok {
+				tt = &tagTotal{name: t}
+				byTag[t] = tt
+				byTagCat[t] = map[string]*tagTotal{}
+			}
+
+			tt.add(xa)
+
+			parent := s.CategoryOf(xa)
+
+			ct, ok := byTagCat[t][parent]
+			if !ok {
+				ct = &tagTotal{name: parent}
+				byTagCat[t][parent] = ct
+			}
+
+			ct.add(xa)
+		}
+	}
+
+	tags := []*tagTotal{}
+	cats := map[string][]*tagTotal{}
+
+	for t, tt := range byTag {
+		tags = append(tags, tt)
+
+		for _, ct := range byTagCat[t] {
+			cats[t] = append(cats[t], ct)
+		}
+
+		slices.SortFunc(cats[t], func(a, b *tagTotal) int {
+			return cmp.Or(
+				cmp.Compare(b.debitAmt+b.creditAmt, a.debitAmt+a.creditAmt),
+				cmp.Compare(a.name, b.name))
+		})
+	}
+
+	slices.SortFunc(tags, func(a, b *tagTotal) int {
+		return cmp.Compare(a.name, b.name)
+	})
+
+	return tags, cats
+}
+
+// tagReport will report the totals of the transactions in the category for
+// each tag and, beneath each tag, the totals for each category in which
+// the tagged transactions are found
+func (s *summaries) tagReport(prog *prog, cat string) {
+	const (
+		floatColWidth = 10
+		floatColPrec  = 2
+		countColWidth = 5
+	)
+
+	if s.Summary(cat) == nil {
+		fmt.Fprintf(os.Stderr, "*** category: %q is not recognised\n", cat)
+		return
+	}
+
+	tags, cats := s.tagTotals(cat)
+
+	nameWidth := len("Tag")
+
+	for _, tt := range tags {
+		nameWidth = max(nameWidth, len(tt.name))
+
+		for _, ct := range cats[tt.name] {
+			nameWidth = max(nameWidth, tabWidth+len(ct.name))
+		}
+	}
+
+	floatCol := colfmt.Float{
+		W:    floatColWidth,
+		Prec: floatColPrec,
+		Zeroes: &colfmt.FloatZeroHandler{
+			Handle:  true,
+			Replace: "",
+		},
+	}
+
+	rpt := col.NewReportOrPanic(col.NewHeaderOrPanic(), prog.out,
+		col.New(&colfmt.String{W: nameWidth}, "Tag", "Category"),
+		col.New(&colfmt.Int{W: countColWidth}, "Count"),
+		col.New(&floatCol, "Debit", "Amount"),
+		col.New(&floatCol, "Credit", "Amount"),
+		col.New(&floatCol, "Nett", "Amount"),
+	)
+
+	for _, tt := range tags {
+		tt.printRow(rpt, 0)
+
+		for _, ct := range cats[tt.name] {
+			ct.printRow(rpt, 1)
+		}
+	}
+}
+
+// printRow prints the totals, with the name indented
+func (tt *tagTotal) printRow(rpt *col.Report, indent int) {
+	err := rpt.PrintRow(
+		strings.Repeat(" ", tabWidth*indent)+tt.name,
+		len(tt.locations),
+		tt.debitAmt,
+		tt.creditAmt,
+		tt.creditAmt-tt.debitAmt)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Couldn't print the row:", err)
+	}
+}
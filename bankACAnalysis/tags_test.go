@@ -0,0 +1,115 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nickwells/personal-utils/bankACAnalysis/bankac"
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestTagTotals(t *testing.T) {
+	tree := bankac.NewTree()
+	for _, entry := range [][2]string{
+		{bankac.CatAll, "food"},
+		{bankac.CatAll, "household"},
+		{"food", "TESCO"},
+		{"food", "CAFE"},
+		{"household", "household: TESCO"},
+	} {
+		if err := tree.AddParent(entry[0], entry[1]); err != nil {
+			t.Fatal("couldn't build the tree:", err)
+		}
+	}
+
+	mkXactn := func(lineNum int, summName string, amt float64,
+		tags ...string,
+	) Xactn {
+		return Xactn{
+			Xactn: bankac.Xactn{
+				FileName: "jan.csv",
+				LineNum:  lineNum,
+				Date:     mkDate(2024, time.January, lineNum),
+				DebitAmt: amt,
+			},
+			SummName: summName,
+			Tags:     tags,
+		}
+	}
+
+	// the first two entries are the parts of a split transaction
+	s := &summaries{
+		Categoriser: bankac.NewCategoriser(bankac.NewSummaries(tree)),
+		xactns: []Xactn{
+			mkXactn(3, "TESCO", 6, "holiday", "kids"),
+			mkXactn(3, "household: TESCO", 4, "holiday", "kids"),
+			mkXactn(5, "CAFE", 3, "holiday"),
+		},
+	}
+
+	type expTotal struct {
+		name     string
+		count    int
+		debitAmt float64
+	}
+
+	toExp := func(tts []*tagTotal) []expTotal {
+		totals := []expTotal{}
+		for _, tt := range tts {
+			totals = append(totals,
+				expTotal{tt.name, len(tt.locations), tt.debitAmt})
+		}
+
+		return totals
+	}
+
+	testCases := []struct {
+		testhelper.ID
+		cat     string
+		expTags []expTotal
+		expCats map[string][]expTotal
+	}{
+		{
+			ID:  testhelper.MkID("all"),
+			cat: bankac.CatAll,
+			expTags: []expTotal{
+				{name: "holiday", count: 2, debitAmt: 13},
+				{name: "kids", count: 1, debitAmt: 10},
+			},
+			expCats: map[string][]expTotal{
+				"holiday": {
+					{name: "food", count: 2, debitAmt: 9},
+					{name: "household", count: 1, debitAmt: 4},
+				},
+				"kids": {
+					{name: "food", count: 1, debitAmt: 6},
+					{name: "household", count: 1, debitAmt: 4},
+				},
+			},
+		},
+		{
+			ID:  testhelper.MkID("household"),
+			cat: "household",
+			expTags: []expTotal{
+				{name: "holiday", count: 1, debitAmt: 4},
+				{name: "kids", count: 1, debitAmt: 4},
+			},
+			expCats: map[string][]expTotal{
+				"holiday": {{name: "household", count: 1, debitAmt: 4}},
+				"kids":    {{name: "household", count: 1, debitAmt: 4}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tags, cats := s.tagTotals(tc.cat)
+
+		testhelper.DiffValsReport(t, tc.IDStr(), "tag totals",
+			toExp(tags), tc.expTags)
+
+		for tag, expCats := range tc.expCats {
+			testhelper.DiffValsReport(t, tc.IDStr(), "category totals: "+tag,
+				toExp(cats[tag]), expCats)
+		}
+	}
+}
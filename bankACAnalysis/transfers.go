@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/nickwells/personal-utils/bankACAnalysis/bankac"
+	"github.com/nickwells/verbose.mod/verbose"
+)
+
+// What to do with the transfers between our own accounts
+const (
+	transfersCategorise = "categorise"
+	transfersExclude    = "exclude"
+)
+
+const dfltTransferDays = 3
+
+// setOwnAccounts parses the own-account values into a map from the account
+// identifier to the account
+func (prog *prog) setOwnAccounts() error {
+	prog.ownAccounts = bankac.OwnAccounts{}
+
+	for _, val := range prog.ownAccountDefs {
+		oa, err := bankac.ParseOwnAccount(val)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := prog.ownAccounts[oa.ID]; ok {
+			return fmt.Errorf("own account %q is given more than once", oa.ID)
+		}
+
+		prog.ownAccounts[oa.ID] = oa
+	}
+
+	return nil
+}
+
+// matchTransfers marks the debits in one of our own accounts which match
+// a credit in another of our accounts as transfers or, if they are to be
+// excluded, removes them. Any unmatched transactions which look like
+// transfers are reported.
+func (prog *prog) matchTransfers(xas []Xactn) []Xactn {
+	if len(prog.ownAccounts) == 0 {
+		return xas
+	}
+
+	prog.ownAccounts.MarkTransfers(xas, prog.transferDays)
+
+	kept := make([]Xactn, 0, len(xas))
+
+	for _, xa := range xas {
+		switch {
+		case xa.IsTransfer:
+			verbose.Printf("%s:%d: %q is a transfer\n",
+				xa.FileName, xa.LineNum, xa.Desc)
+
+			if prog.transfers == transfersExclude {
+				continue
+			}
+		case prog.ownAccounts.LooksLikeTransfer(xa.Xactn) &&
+			prog.dates.contains(xa.Date):
+			fmt.Fprintf(os.Stderr, "%s:%d: unmatched transfer: %s %s %.2f\n",
+				xa.FileName, xa.LineNum,
+				xa.Date.Format(rptDateFormat), xa.Desc,
+				xa.CreditAmt-xa.DebitAmt)
+		}
+
+		kept = append(kept, xa)
+	}
+
+	return kept
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nickwells/personal-utils/bankACAnalysis/bankac"
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+const (
+	testCurrentAC = "11-22-33 123"
+	testSavingsAC = "44-55-66 456"
+)
+
+// mkACXactn returns a transaction in the account with the signed amount,
+// its line number is its day of the month
+func mkACXactn(acct string, day int, desc string, amt float64) Xactn {
+	xa := Xactn{Xactn: bankac.Xactn{
+		FileName: acct,
+		LineNum:  day,
+		Date:     mkDate(2024, time.March, day),
+		Desc:     desc,
+		Account:  acct,
+	}}
+	xa.SetAmount(amt)
+
+	return xa
+}
+
+func TestMatchTransfers(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		transfers   string
+		xas         []Xactn
+		expKept     []string
+		expMatched  []string
+		expWarnings []string
+	}{
+		{
+			ID:        testhelper.MkID("matched pair"),
+			transfers: transfersCategorise,
+			xas: []Xactn{
+				mkACXactn(testCurrentAC, 1, "TO SAVINGS", -100),
+				mkACXactn(testSavingsAC, 2, "FROM CURRENT", 100),
+				mkACXactn(testCurrentAC, 3, "TESCO", -100),
+			},
+			expKept: []string{
+				testCurrentAC + ":1",
+				testSavingsAC + ":2",
+				testCurrentAC + ":3",
+			},
+			expMatched: []string{
+				testCurrentAC + ":1",
+				testSavingsAC + ":2",
+			},
+		},
+		{
+			ID:        testhelper.MkID("matched pair, excluded"),
+			transfers: transfersExclude,
+			xas: []Xactn{
+				mkACXactn(testCurrentAC, 1, "TO SAVINGS", -100),
+				mkACXactn(testSavingsAC, 2, "FROM CURRENT", 100),
+				mkACXactn(testCurrentAC, 3, "TESCO", -100),
+			},
+			expKept:    []string{testCurrentAC + ":3"},
+			expMatched: []string{},
+		},
+		{
+			ID:        testhelper.MkID("amounts differ"),
+			transfers: transfersCategorise,
+			xas: []Xactn{
+				mkACXactn(testCurrentAC, 1, "TO SAVINGS", -100),
+				mkACXactn(testSavingsAC, 2, "FROM CURRENT", 99),
+			},
+			expKept: []string{
+				testCurrentAC + ":1",
+				testSavingsAC + ":2",
+			},
+			expMatched: []string{},
+			expWarnings: []string{
+				testCurrentAC + ":1: unmatched transfer",
+				testSavingsAC + ":2: unmatched transfer",
+			},
+		},
+		{
+			ID:        testhelper.MkID("too far apart"),
+			transfers: transfersCategorise,
+			xas: []Xactn{
+				mkACXactn(testCurrentAC, 1, "TO 456", -100),
+				mkACXactn(testSavingsAC, 5, "CREDIT", 100),
+			},
+			expKept: []string{
+				testCurrentAC + ":1",
+				testSavingsAC + ":5",
+			},
+			expMatched: []string{},
+			expWarnings: []string{
+				testCurrentAC + ":1: unmatched transfer",
+			},
+		},
+		{
+			ID:        testhelper.MkID("same account"),
+			transfers: transfersCategorise,
+			xas: []Xactn{
+				mkACXactn(testCurrentAC, 1, "REFUND", -100),
+				mkACXactn(testCurrentAC, 2, "REFUND", 100),
+			},
+			expKept: []string{
+				testCurrentAC + ":1",
+				testCurrentAC + ":2",
+			},
+			expMatched: []string{},
+		},
+		{
+			ID:        testhelper.MkID("two credits, the closest is matched"),
+			transfers: transfersCategorise,
+			xas: []Xactn{
+				mkACXactn(testSavingsAC, 1, "CREDIT", 100),
+				mkACXactn(testCurrentAC, 4, "TO SAVINGS", -100),
+				mkACXactn(testSavingsAC, 5, "CREDIT", 100),
+			},
+			expKept: []string{
+				testSavingsAC + ":1",
+				testCurrentAC + ":4",
+				testSavingsAC + ":5",
+			},
+			expMatched: []string{
+				testCurrentAC + ":4",
+				testSavingsAC + ":5",
+			},
+		},
+		{
+			ID:        testhelper.MkID("two debits, the earliest is matched"),
+			transfers: transfersCategorise,
+			xas: []Xactn{
+				mkACXactn(testCurrentAC, 3, "TO SAVINGS", -100),
+				mkACXactn(testSavingsAC, 2, "CREDIT", 100),
+				mkACXactn(testCurrentAC, 1, "TO SAVINGS", -100),
+			},
+			expKept: []string{
+				testCurrentAC + ":3",
+				testSavingsAC + ":2",
+				testCurrentAC + ":1",
+			},
+			expMatched: []string{
+				testSavingsAC + ":2",
+				testCurrentAC + ":1",
+			},
+			expWarnings: []string{
+				testCurrentAC + ":3: unmatched transfer",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		prog := newProg()
+		prog.transfers = tc.transfers
+		prog.ownAccountDefs = []string{
+			"current=" + testCurrentAC,
+			"savings=" + testSavingsAC,
+		}
+
+		if err := prog.setOwnAccounts(); err != nil {
+			t.Fatal("couldn't set the own accounts:", err)
+		}
+
+		var kept []Xactn
+
+		warnings := captureStderr(t, func() {
+			kept = prog.matchTransfers(tc.xas)
+		})
+
+		keptLocs, matchedLocs := []string{}, []string{}
+
+		for _, xa := range kept {
+			keptLocs = append(keptLocs, xa.Location())
+			if xa.IsTransfer {
+				matchedLocs = append(matchedLocs, xa.Location())
+			}
+		}
+
+		testhelper.DiffValsReport(t, tc.IDStr(), "kept",
+			keptLocs, tc.expKept)
+		testhelper.DiffValsReport(t, tc.IDStr(), "matched",
+			matchedLocs, tc.expMatched)
+		testhelper.DiffInt(t, tc.IDStr(), "warnings",
+			strings.Count(warnings, "unmatched transfer"),
+			len(tc.expWarnings))
+
+		for _, expWarning := range tc.expWarnings {
+			if !strings.Contains(warnings, expWarning) {
+				t.Log(tc.IDStr())
+				t.Errorf("\t: the warning %q was not reported, got:\n%s",
+					expWarning, warnings)
+			}
+		}
+	}
+}
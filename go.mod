@@ -16,6 +16,7 @@ require (
 	github.com/nickwells/verbose.mod v1.1.22
 	github.com/nickwells/versionparams.mod v1.2.26
 	github.com/nickwells/xdg.mod v1.0.12
+	golang.org/x/term v0.42.0
 )
 
 require (
@@ -29,5 +30,4 @@ require (
 	github.com/nickwells/pager.mod v1.1.0 // indirect
 	golang.org/x/exp v0.0.0-20260410095643-746e56fc9e2f // indirect
 	golang.org/x/sys v0.43.0 // indirect
-	golang.org/x/term v0.42.0 // indirect
 )